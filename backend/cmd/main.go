@@ -1,40 +1,185 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"main/internal/config"
 	"main/internal/handlers"
 	"main/internal/middleware"
+	"main/internal/repository"
+	"main/internal/routes"
+	"main/internal/utils"
 	"main/pkg/database"
+	"main/pkg/mailer"
+	"main/pkg/ratelimit"
+	"main/pkg/tracing"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML configuration file")
+	flag.Parse()
+
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(*configPath, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Load configuration
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	// Initialize logging
+	if err := utils.InitLogger(cfg.Log.LoggerConfig()); err != nil {
+		log.Fatal("Failed to initialize logger:", err)
+	}
+	utils.Log.WithField("config", cfg.String()).Info("Configuration loaded")
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		utils.Log.WithError(err).Fatal("Failed to initialize tracing")
+	}
+
 	// Initialize the database
-	if err := database.InitDB(); err != nil {
-		log.Fatal("Failed to initialize database:", err)
+	if err := database.InitDB(cfg.Database); err != nil {
+		utils.Log.WithError(err).Fatal("Failed to initialize database")
+	}
+	if cfg.Database.AutoMigrate {
+		applied, err := database.Migrate(context.Background())
+		for _, migration := range applied {
+			utils.Log.WithField("migration", migration.String()).Info("Applied migration")
+		}
+		if err != nil {
+			utils.Log.WithError(err).Fatal("Failed to apply migrations")
+		}
+	}
+
+	// Configure outgoing mail, signed links and handler settings
+	if err := mailer.Init(mailer.Config{
+		Host:        cfg.Mail.Host,
+		Port:        cfg.Mail.Port,
+		Username:    cfg.Mail.Username,
+		Password:    cfg.Mail.Password,
+		From:        cfg.Mail.From,
+		Development: cfg.Server.Development(),
+	}); err != nil {
+		utils.Log.WithError(err).Fatal("Failed to configure mail")
+	}
+	utils.SetSigningKey(cfg.Auth.Secret)
+	trustedProxies, err := utils.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		utils.Log.WithError(err).Fatal("Invalid server.trusted_proxies")
 	}
+	utils.SetTrustedProxies(trustedProxies)
+	handlers.Configure(cfg)
 
-	// Initialize routes
-	router := http.NewServeMux()
+	middleware.SetCORSOptions(middleware.CORSOptions(cfg.CORS))
+
+	// Apply per-route rate limits and share them across replicas when Redis is available
+	for route, limit := range cfg.RateLimits {
+		middleware.SetRouteLimit(route, middleware.RateLimit(limit))
+	}
+	var redisClient *redis.Client
+	if cfg.Redis.URL != "" {
+		opts, err := redis.ParseURL(cfg.Redis.URL)
+		if err != nil {
+			utils.Log.WithError(err).Fatal("Invalid redis.url")
+		}
+		redisClient = redis.NewClient(opts)
+		middleware.SetLimiterStore(ratelimit.NewRedisStore(redisClient, "ratelimit:"))
+	}
 
-	// Apply middleware and handlers
-	router.HandleFunc("/post", middleware.RateLimiter(handlers.PostHandler))
-	router.HandleFunc("/get", middleware.RateLimiter(handlers.GetHandler))
-	router.HandleFunc("/users", middleware.RateLimiter(middleware.CORS(handlers.UserHandler)))
-	router.HandleFunc("/user/create", middleware.RateLimiter(middleware.CORS(handlers.CreateUser)))
-	router.HandleFunc("/user/update", middleware.RateLimiter(middleware.CORS(handlers.UpdateUserHandler)))
-	router.HandleFunc("/user/delete", middleware.RateLimiter(middleware.CORS(handlers.DeleteUserHandler)))
-	router.HandleFunc("/user/get", middleware.RateLimiter(middleware.CORS(handlers.GetUserHandler)))
-	router.HandleFunc("/send-email", middleware.CORS(handlers.SendEmailHandler))
+	// Create or promote the bootstrap administrator
+	if cfg.Auth.AdminEmail != "" {
+		if err := database.EnsureAdmin(cfg.Auth.AdminName, cfg.Auth.AdminEmail, cfg.Auth.AdminPassword); err != nil {
+			utils.Log.WithError(err).Fatal("Failed to bootstrap admin account")
+		}
+	}
 
-	// Serve static files
-	fs := http.FileServer(http.Dir("./static"))
-	router.Handle("/", fs)
+	// Initialize routes with handlers backed by the database
+	users := repository.NewGormUserRepository(database.DB)
+	router := routes.New(cfg, routes.Handlers{
+		Users: handlers.NewUserHandlers(users),
+		Posts: handlers.NewPostHandlers(repository.NewGormPostRepository(database.DB)),
+		Auth:  handlers.NewAuthHandlers(users),
+	})
 
 	// Start server
-	log.Println("Server is running on port 8080...")
-	if err := http.ListenAndServe(":8080", router); err != nil {
-		log.Fatal("Server failed to start:", err)
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           tracing.Middleware(utils.RequestIDMiddleware(utils.LoggerMiddleware(utils.MetricsMiddleware(router)))),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
+	stop, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		utils.Log.WithField("port", cfg.Server.Port).Info("Server is running")
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		utils.Log.WithError(err).Fatal("Server failed to start")
+	case <-stop.Done():
+		cancel()
+		utils.Log.Info("Shutdown signal received, draining connections")
+	}
+
+	// Fail readiness first and give load balancers time to notice before
+	// the listener closes
+	handlers.StartDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	// Drain in-flight requests and background work, then release resources
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+
+	if err := server.Shutdown(ctx); err != nil {
+		utils.Log.WithError(err).Error("Server did not shut down cleanly")
+	}
+	if err := handlers.WaitForBackgroundTasks(ctx); err != nil {
+		utils.Log.WithError(err).Error("Background tasks did not finish before the deadline")
+	}
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			utils.Log.WithError(err).Error("Failed to close Redis client")
+		}
+	}
+	if err := database.Close(); err != nil {
+		utils.Log.WithError(err).Error("Failed to close database")
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		utils.Log.WithError(err).Error("Failed to flush traces")
+	}
+
+	utils.Log.Info("Server stopped")
+	if err := utils.CloseLogger(); err != nil {
+		log.Println("Failed to close log file:", err)
 	}
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"main/internal/config"
+	"main/pkg/database"
+	"main/pkg/migrate"
+)
+
+const migrateUsage = `usage: server [-config file] migrate <command>
+
+commands:
+  up                  apply all pending migrations
+  down [steps]        revert the last applied migration, or the last steps
+  status              list migrations and when they were applied
+  create [-dir dir] name
+                      add empty up and down files for a new migration to
+                      the directory of every database driver
+`
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(configPath string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+
+	if command == "create" {
+		flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+		dir := flags.String("dir", "pkg/database/migrations", "directory holding one migrations directory per driver")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New(migrateUsage)
+		}
+		for _, driver := range database.Drivers() {
+			up, down, err := migrate.Create(filepath.Join(*dir, driver), flags.Arg(0))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created %s\nCreated %s\n", up, down)
+		}
+		return nil
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("load configuration: %w", err)
+	}
+	if err := database.InitDB(cfg.Database); err != nil {
+		return fmt.Errorf("initialize database: %w", err)
+	}
+	defer database.Close()
+
+	migrator, err := database.Migrator()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Println("Applied", migration)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number, got %q", args[0])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Println("Reverted", migration)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "MIGRATION\tAPPLIED AT")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Up == "" {
+				applied += " (no source in this build)"
+			}
+			fmt.Fprintf(out, "%s\t%s\n", status.Migration, applied)
+		}
+		return out.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,514 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"main/internal/utils"
+)
+
+// Config holds every setting the server needs. Values come from defaults,
+// then an optional YAML file, then environment variables, each layer
+// overriding the previous one. Fields tagged secret are redacted by String.
+type Config struct {
+	Server     ServerConfig               `yaml:"server" json:"server"`
+	Database   DatabaseConfig             `yaml:"database" json:"database"`
+	Mail       MailConfig                 `yaml:"mail" json:"mail"`
+	Log        LogConfig                  `yaml:"log" json:"log"`
+	Auth       AuthConfig                 `yaml:"auth" json:"auth"`
+	Redis      RedisConfig                `yaml:"redis" json:"redis"`
+	Metrics    MetricsConfig              `yaml:"metrics" json:"metrics"`
+	Tracing    TracingConfig              `yaml:"tracing" json:"tracing"`
+	CORS       CORSConfig                 `yaml:"cors" json:"cors"`
+	RateLimits map[string]RateLimitConfig `yaml:"rate_limits" json:"rate_limits"`
+}
+
+type ServerConfig struct {
+	// Environment is development or production. Only development may run
+	// without an SMTP relay.
+	Environment   string `yaml:"environment" json:"environment" env:"APP_ENV"`
+	Port          int    `yaml:"port" json:"port" env:"PORT"`
+	PublicBaseURL string `yaml:"public_base_url" json:"public_base_url" env:"APP_BASE_URL"`
+	StaticDir     string `yaml:"static_dir" json:"static_dir" env:"STATIC_DIR"`
+	// LegacyRoutes keeps serving the deprecated pre-/api/v1 paths.
+	LegacyRoutes bool `yaml:"legacy_routes" json:"legacy_routes" env:"LEGACY_ROUTES"`
+
+	// Timeouts and limits for http.Server. A zero timeout disables it.
+	ReadTimeout       time.Duration `yaml:"read_timeout" json:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" json:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" json:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" json:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" json:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	// ShutdownTimeout bounds how long in-flight requests and background
+	// work may take to finish after SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" json:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// DrainDelay keeps serving with /readyz failing for this long before
+	// shutdown starts, so load balancers can stop routing to the instance.
+	DrainDelay time.Duration `yaml:"drain_delay" json:"drain_delay" env:"SERVER_DRAIN_DELAY"`
+	// TrustedProxies lists the load balancers, as CIDR ranges or single
+	// addresses, whose X-Forwarded-For and X-Real-IP headers are believed.
+	// Empty ignores the headers and uses the connection's peer address.
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+}
+
+type DatabaseConfig struct {
+	// Driver is postgres or sqlite.
+	Driver string `yaml:"driver" json:"driver" env:"DB_DRIVER"`
+	// DSN, when set, is used as is and the individual fields are ignored.
+	DSN string `yaml:"dsn" json:"dsn" env:"DATABASE_URL" secret:"true"`
+	// Path is the SQLite database file.
+	Path     string `yaml:"path" json:"path" env:"DB_PATH"`
+	Host     string `yaml:"host" json:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" json:"port" env:"DB_PORT"`
+	User     string `yaml:"user" json:"user" env:"DB_USER"`
+	Password string `yaml:"password" json:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" json:"name" env:"DB_NAME"`
+	SSLMode  string `yaml:"sslmode" json:"sslmode" env:"DB_SSLMODE"`
+	// AutoMigrate applies pending migrations at startup. Disable it to run
+	// `migrate up` as a separate deploy step instead.
+	AutoMigrate bool `yaml:"auto_migrate" json:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+type MailConfig struct {
+	// An empty Host makes the mailer log messages instead of sending them,
+	// which is only allowed in development.
+	Host     string `yaml:"host" json:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" json:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" json:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" json:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" json:"from" env:"SMTP_FROM"`
+	// SupportAddress receives messages sent through the contact form.
+	SupportAddress string `yaml:"support_address" json:"support_address" env:"SUPPORT_EMAIL"`
+}
+
+type LogConfig struct {
+	Level          string `yaml:"level" json:"level" env:"LOG_LEVEL"`
+	Path           string `yaml:"path" json:"path" env:"LOG_PATH"`
+	EnableConsole  bool   `yaml:"console" json:"console" env:"LOG_CONSOLE"`
+	EnableJSON     bool   `yaml:"json" json:"json" env:"LOG_JSON"`
+	EnableRotation bool   `yaml:"rotation" json:"rotation" env:"LOG_ROTATION"`
+	MaxSize        int    `yaml:"max_size_mb" json:"max_size_mb" env:"LOG_MAX_SIZE_MB"`
+	MaxBackups     int    `yaml:"max_backups" json:"max_backups" env:"LOG_MAX_BACKUPS"`
+	MaxAge         int    `yaml:"max_age_days" json:"max_age_days" env:"LOG_MAX_AGE_DAYS"`
+	EnableCaller   bool   `yaml:"caller" json:"caller" env:"LOG_CALLER"`
+}
+
+type AuthConfig struct {
+	// Secret signs verification links and login challenges and must be
+	// shared by every replica. It is required outside development, where an
+	// empty secret is replaced by a random one at startup.
+	Secret        string `yaml:"secret" json:"secret" env:"APP_SECRET" secret:"true"`
+	AdminName     string `yaml:"admin_name" json:"admin_name" env:"ADMIN_NAME"`
+	AdminEmail    string `yaml:"admin_email" json:"admin_email" env:"ADMIN_EMAIL"`
+	AdminPassword string `yaml:"admin_password" json:"admin_password" env:"ADMIN_PASSWORD" secret:"true"`
+}
+
+type RedisConfig struct {
+	// URL enables the shared rate-limit store, e.g. redis://localhost:6379/0.
+	URL string `yaml:"url" json:"url" env:"REDIS_URL" secret:"true"`
+}
+
+type MetricsConfig struct {
+	// Enabled exposes Prometheus metrics at Path on the main listener.
+	Enabled bool   `yaml:"enabled" json:"enabled" env:"METRICS_ENABLED"`
+	Path    string `yaml:"path" json:"path" env:"METRICS_PATH"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, otlp or stdout.
+	Exporter string `yaml:"exporter" json:"exporter" env:"TRACING_EXPORTER"`
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	Endpoint    string  `yaml:"endpoint" json:"endpoint" env:"TRACING_ENDPOINT"`
+	ServiceName string  `yaml:"service_name" json:"service_name" env:"TRACING_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" json:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins lists exact origins (https://app.example.com), subdomain
+	// wildcards (https://*.example.com) or "*" for any origin. List-valued
+	// environment variables are comma-separated.
+	AllowedOrigins   []string `yaml:"allowed_origins" json:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowed_methods" json:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowed_headers" json:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string `yaml:"exposed_headers" json:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool     `yaml:"allow_credentials" json:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	// MaxAge is how long browsers may cache preflight responses.
+	MaxAge time.Duration `yaml:"max_age" json:"max_age" env:"CORS_MAX_AGE"`
+}
+
+type RateLimitConfig struct {
+	Requests int           `yaml:"requests" json:"requests"`
+	Window   time.Duration `yaml:"window" json:"window"`
+	Burst    int           `yaml:"burst" json:"burst"`
+	MaxWait  time.Duration `yaml:"max_wait" json:"max_wait"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Environment:       "production",
+			Port:              8080,
+			PublicBaseURL:     "http://localhost:8080",
+			StaticDir:         "./static",
+			LegacyRoutes:      true,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:      "postgres",
+			Path:        "./data/social_pub.db",
+			Host:        "localhost",
+			Port:        5432,
+			User:        "postgres",
+			Name:        "social_pub",
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		},
+		Mail: MailConfig{
+			Port: 587,
+		},
+		Log: LogConfig{
+			Level:          "info",
+			Path:           "./logs",
+			EnableConsole:  true,
+			EnableJSON:     true,
+			EnableRotation: true,
+			MaxSize:        100,
+			MaxBackups:     7,
+			MaxAge:         30,
+		},
+		Auth: AuthConfig{
+			AdminName: "admin",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "social-pub",
+			SampleRatio: 1,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:3000"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-Requested-With", "X-Request-ID", "traceparent", "tracestate"},
+			ExposedHeaders: []string{"X-Request-ID", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "Deprecation", "Link"},
+			MaxAge:         10 * time.Minute,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the YAML file at path (if
+// path is not empty) and the environment, then validates it.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read config file: %v", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse config file: %v", err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return cfg, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	switch c.Server.Environment {
+	case "development", "production":
+	default:
+		errs = append(errs, fmt.Errorf("server.environment must be development or production, got %q", c.Server.Environment))
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if u, err := url.Parse(c.Server.PublicBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("server.public_base_url must be an absolute URL, got %q", c.Server.PublicBaseURL))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+	if c.Server.MaxHeaderBytes < 0 {
+		errs = append(errs, fmt.Errorf("server.max_header_bytes must not be negative, got %d", c.Server.MaxHeaderBytes))
+	}
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("server.drain_delay must not be negative, got %s", c.Server.DrainDelay))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("server.shutdown_timeout must be positive, got %s", c.Server.ShutdownTimeout))
+	}
+	if _, err := utils.ParseTrustedProxies(c.Server.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("server.trusted_proxies: %v", err))
+	}
+
+	switch c.Database.Driver {
+	case "postgres", "sqlite":
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be postgres or sqlite, got %q", c.Database.Driver))
+	}
+	if c.Database.Driver == "sqlite" && c.Database.DSN == "" && c.Database.Path == "" {
+		errs = append(errs, errors.New("database.path is required for sqlite when database.dsn is not set"))
+	}
+	if c.Database.Driver == "postgres" && c.Database.DSN == "" {
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host is required when database.dsn is not set"))
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database.port must be between 1 and 65535, got %d", c.Database.Port))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name is required when database.dsn is not set"))
+		}
+	}
+
+	if c.Mail.Host == "" && !c.Server.Development() {
+		errs = append(errs, errors.New("mail.host is required outside development; set server.environment to development to log mail instead"))
+	}
+	if c.Mail.Host != "" && (c.Mail.Port < 1 || c.Mail.Port > 65535) {
+		errs = append(errs, fmt.Errorf("mail.port must be between 1 and 65535, got %d", c.Mail.Port))
+	}
+	if c.Mail.From != "" && !utils.IsValidEmail(c.Mail.From) {
+		errs = append(errs, errors.New("mail.from is not a valid email address"))
+	}
+	if c.Mail.SupportAddress != "" && !utils.IsValidEmail(c.Mail.SupportAddress) {
+		errs = append(errs, errors.New("mail.support_address is not a valid email address"))
+	}
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %v", err))
+	}
+	if c.Log.Path == "" {
+		errs = append(errs, errors.New("log.path is required"))
+	}
+	if c.Log.MaxSize < 0 || c.Log.MaxBackups < 0 || c.Log.MaxAge < 0 {
+		errs = append(errs, errors.New("log.max_size_mb, log.max_backups and log.max_age_days must not be negative"))
+	}
+
+	if c.Auth.Secret == "" && !c.Server.Development() {
+		errs = append(errs, errors.New("auth.secret is required outside development, so signed links survive restarts and work across replicas"))
+	}
+	if c.Auth.AdminEmail != "" && !utils.IsValidEmail(c.Auth.AdminEmail) {
+		errs = append(errs, errors.New("auth.admin_email is not a valid email address"))
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("metrics.path must start with '/', got %q", c.Metrics.Path))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be none, otlp or stdout, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio))
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				errs = append(errs, errors.New("cors.allowed_origins must not contain \"*\" when cors.allow_credentials is set"))
+			}
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("cors.allowed_origins: %q is not an origin like https://app.example.com or https://*.example.com", origin))
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("cors.max_age must not be negative"))
+	}
+
+	for route, limit := range c.RateLimits {
+		if limit.Requests < 0 || limit.Window < 0 || limit.Burst < 0 || limit.MaxWait < 0 {
+			errs = append(errs, fmt.Errorf("rate_limits.%s must not contain negative values", route))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Development reports whether the server runs in the development
+// environment.
+func (c ServerConfig) Development() bool {
+	return c.Environment == "development"
+}
+
+// DatabaseDSN returns the connection string for the configured driver.
+func (c DatabaseConfig) DatabaseDSN() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	if c.Driver == "sqlite" {
+		// Enforce foreign keys like Postgres does and wait for locks held by
+		// other connections instead of failing immediately
+		return "file:" + c.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	}
+	parts := []string{
+		"host=" + quoteDSNValue(c.Host),
+		"port=" + strconv.Itoa(c.Port),
+		"dbname=" + quoteDSNValue(c.Name),
+		"sslmode=" + quoteDSNValue(c.SSLMode),
+	}
+	if c.User != "" {
+		parts = append(parts, "user="+quoteDSNValue(c.User))
+	}
+	if c.Password != "" {
+		parts = append(parts, "password="+quoteDSNValue(c.Password))
+	}
+	return strings.Join(parts, " ")
+}
+
+// LoggerConfig converts the log settings for utils.InitLogger.
+func (c LogConfig) LoggerConfig() utils.LogConfig {
+	return utils.LogConfig{
+		LogLevel:        c.Level,
+		LogPath:         c.Path,
+		EnableConsole:   c.EnableConsole,
+		EnableJSON:      c.EnableJSON,
+		EnableRotation:  c.EnableRotation,
+		MaxSize:         c.MaxSize,
+		MaxBackups:      c.MaxBackups,
+		MaxAge:          c.MaxAge,
+		EnableCallerLog: c.EnableCaller,
+	}
+}
+
+// Redacted returns a copy with every secret replaced by a placeholder.
+func (c Config) Redacted() Config {
+	redacted := c
+	redact(reflect.ValueOf(&redacted).Elem())
+	return redacted
+}
+
+// String renders the redacted configuration as JSON, safe for logs.
+func (c Config) String() string {
+	data, err := json.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("<config: %v>", err)
+	}
+	return string(data)
+}
+
+const redactedValue = "[REDACTED]"
+
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			redact(field)
+		case t.Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "":
+			field.SetString(redactedValue)
+		}
+	}
+}
+
+// applyEnv overrides every field carrying an env tag whose variable is set.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, raw); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(raw)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case []string:
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every variable the configuration reads for one test, so
+// the host environment cannot leak into it.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	var walk func(typ reflect.Type)
+	walk = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(field.Type)
+				continue
+			}
+			if name := field.Tag.Get("env"); name != "" {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}))
+}
+
+// production returns a valid configuration for a production deployment.
+func production() Config {
+	cfg := Default()
+	cfg.Mail.Host = "smtp.example.com"
+	cfg.Auth.Secret = "0123456789abcdef0123456789abcdef"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		desc    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"production", func(cfg *Config) {}, ""},
+		{"development without mail or secret", func(cfg *Config) {
+			cfg.Server.Environment = "development"
+			cfg.Mail.Host = ""
+			cfg.Auth.Secret = ""
+		}, ""},
+		{"unknown environment", func(cfg *Config) { cfg.Server.Environment = "staging" }, "server.environment"},
+		{"production without secret", func(cfg *Config) { cfg.Auth.Secret = "" }, "auth.secret is required"},
+		{"production without mail host", func(cfg *Config) { cfg.Mail.Host = "" }, "mail.host is required"},
+		{"port out of range", func(cfg *Config) { cfg.Server.Port = 70000 }, "server.port"},
+		{"relative base URL", func(cfg *Config) { cfg.Server.PublicBaseURL = "/app" }, "server.public_base_url"},
+		{"zero shutdown timeout", func(cfg *Config) { cfg.Server.ShutdownTimeout = 0 }, "server.shutdown_timeout"},
+		{"bad trusted proxy", func(cfg *Config) { cfg.Server.TrustedProxies = []string{"10.0.0.0/40"} }, "server.trusted_proxies"},
+		{"unknown driver", func(cfg *Config) { cfg.Database.Driver = "mysql" }, "database.driver"},
+		{"sqlite without path", func(cfg *Config) {
+			cfg.Database.Driver = "sqlite"
+			cfg.Database.Path = ""
+		}, "database.path"},
+		{"bad sender", func(cfg *Config) { cfg.Mail.From = "noreply" }, "mail.from"},
+		{"bad log level", func(cfg *Config) { cfg.Log.Level = "loud" }, "log.level"},
+		{"bad tracing exporter", func(cfg *Config) { cfg.Tracing.Exporter = "zipkin" }, "tracing.exporter"},
+		{"any origin with credentials", func(cfg *Config) {
+			cfg.CORS.AllowedOrigins = []string{"*"}
+			cfg.CORS.AllowCredentials = true
+		}, "cors.allowed_origins must not contain"},
+		{"origin with a path", func(cfg *Config) { cfg.CORS.AllowedOrigins = []string{"https://app.example.com/login"} }, "cors.allowed_origins"},
+		{"negative rate limit", func(cfg *Config) {
+			cfg.RateLimits = map[string]RateLimitConfig{"auth": {Requests: -1}}
+		}, "rate_limits.auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := production()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	cfg := production()
+	cfg.Server.Port = 0
+	cfg.Log.Path = ""
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "server.port") || !strings.Contains(err.Error(), "log.path") {
+		t.Errorf("err = %v, want both problems reported", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+server:
+  environment: development
+  port: 9000
+  read_timeout: 20s
+database:
+  driver: sqlite
+  path: ./yaml.db
+cors:
+  allowed_origins: [https://yaml.example.com]
+`
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PORT", "9100")
+	t.Setenv("SERVER_READ_TIMEOUT", " 45s ")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, ,https://b.example.com")
+	t.Setenv("LOG_CONSOLE", "false")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 9100 || cfg.Server.ReadTimeout != 45*time.Second {
+		t.Errorf("environment did not override YAML: port %d, read timeout %s", cfg.Server.Port, cfg.Server.ReadTimeout)
+	}
+	if cfg.Database.Driver != "sqlite" || cfg.Database.Path != "./yaml.db" || cfg.Server.Environment != "development" {
+		t.Errorf("YAML did not override defaults: %+v", cfg.Database)
+	}
+	if cfg.Server.WriteTimeout != Default().Server.WriteTimeout || cfg.Log.Level != "info" {
+		t.Error("defaults not kept for settings set nowhere else")
+	}
+	if want := []string{"https://a.example.com", "https://b.example.com"}; !slices.Equal(cfg.CORS.AllowedOrigins, want) {
+		t.Errorf("allowed origins = %v, want %v", cfg.CORS.AllowedOrigins, want)
+	}
+	if cfg.Log.EnableConsole {
+		t.Error("LOG_CONSOLE=false not applied over the default")
+	}
+}
+
+func TestLoadRejectsInvalidEnv(t *testing.T) {
+	tests := map[string]string{
+		"PORT":                 "eighty",
+		"LOG_CONSOLE":          "sometimes",
+		"SERVER_READ_TIMEOUT":  "15",
+		"TRACING_SAMPLE_RATIO": "half",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("APP_ENV", "development")
+			t.Setenv(name, value)
+			_, err := Load("")
+			if err == nil || !strings.Contains(err.Error(), "invalid value for "+name) {
+				t.Errorf("err = %v, want one naming %s", err, name)
+			}
+		})
+	}
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	cfg := production()
+	cfg.Database.DSN = "postgres://app:dsn-password@db/app"
+	cfg.Database.Password = "db-password"
+	cfg.Mail.Password = "smtp-password"
+	cfg.Auth.AdminPassword = "admin-password"
+	cfg.Redis.URL = "redis://:redis-password@cache:6379/0"
+	secrets := []string{"dsn-password", "db-password", "smtp-password", cfg.Auth.Secret, "admin-password", "redis-password"}
+
+	redacted := cfg.Redacted()
+	for _, value := range []string{redacted.Database.DSN, redacted.Database.Password, redacted.Mail.Password,
+		redacted.Auth.Secret, redacted.Auth.AdminPassword, redacted.Redis.URL} {
+		if value != redactedValue {
+			t.Errorf("secret field rendered as %q", value)
+		}
+	}
+	if cfg.Mail.Password != "smtp-password" {
+		t.Error("Redacted modified the original")
+	}
+	if redacted.Mail.Host != cfg.Mail.Host {
+		t.Error("Redacted hid a field not tagged secret")
+	}
+
+	rendered := cfg.String()
+	for _, secret := range secrets {
+		if strings.Contains(rendered, secret) {
+			t.Errorf("String leaks %q", secret)
+		}
+	}
+	if !strings.Contains(rendered, "smtp.example.com") {
+		t.Errorf("String omits ordinary settings: %s", rendered)
+	}
+
+	// Unset secrets stay empty so logs show they are missing
+	if Default().Redacted().Auth.Secret != "" {
+		t.Error("empty secret rendered as redacted")
+	}
+}
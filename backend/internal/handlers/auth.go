@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/utils"
+)
+
+// AuthHandlers serves sign-up, login, sessions, two-factor authentication,
+// password resets and email verification. Accounts are read and written
+// through Users; sessions and one-time tokens are kept in database.DB.
+type AuthHandlers struct {
+	Users repository.UserRepository
+}
+
+func NewAuthHandlers(users repository.UserRepository) *AuthHandlers {
+	return &AuthHandlers{Users: users}
+}
+
+type credentials struct {
+	UserName  string `json:"user_name,omitempty"`
+	UserEmail string `json:"user_email"`
+	Password  string `json:"password"`
+}
+
+func (h *AuthHandlers) Signup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	user := models.User{
+		UserName:  strings.TrimSpace(req.UserName),
+		UserEmail: normalizeEmail(req.UserEmail),
+		Role:      models.RoleUser,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := utils.ValidateUser(user); err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not create account", http.StatusInternalServerError)
+		return
+	}
+	user.PasswordHash = hash
+
+	if err := h.Users.Create(r.Context(), &user); err != nil {
+		if errors.Is(err, utils.ErrDuplicateEmail) {
+			utils.SendErrorResponse(w, "Email already exists", http.StatusConflict)
+			return
+		}
+		utils.SendErrorResponse(w, "Could not create account", http.StatusInternalServerError)
+		return
+	}
+
+	sendVerificationEmailAsync(r.Context(), user)
+
+	auth, err := startSession(r, user)
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not create session", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, models.ResponseData{
+		Status:  "success",
+		Message: "Account created successfully",
+		Data:    auth,
+	})
+}
+
+func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authenticateUser(r.Context(), req.UserEmail, req.Password)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCredentials) {
+			utils.SendErrorResponse(w, "Invalid email or password", http.StatusUnauthorized)
+			return
+		}
+		utils.SendErrorResponse(w, "Could not log in", http.StatusInternalServerError)
+		return
+	}
+
+	if user.TOTPEnabled {
+		utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+			Status:  "success",
+			Message: "Two-factor authentication code required",
+			Data:    newMFAChallenge(*user),
+		})
+		return
+	}
+
+	auth, err := startSession(r, *user)
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not create session", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Logged in successfully",
+		Data:    auth,
+	})
+}
+
+// authenticateUser looks up the account by email and verifies its password.
+// Unknown emails and wrong passwords both yield utils.ErrInvalidCredentials.
+func (h *AuthHandlers) authenticateUser(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := h.Users.FindByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, utils.ErrUserNotFound) {
+		// Check against no hash so unknown emails take as long as wrong passwords
+		user = &models.User{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !utils.CheckPassword(user.PasswordHash, password) {
+		return nil, utils.ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/utils"
+	"main/pkg/database"
+	"main/pkg/mailer"
+)
+
+// newAuthHandlers returns AuthHandlers on a fresh SQLite database, which the
+// session and token tables need, and records outgoing mail.
+func newAuthHandlers(t *testing.T) (*AuthHandlers, *recordingSender) {
+	t.Helper()
+	useTestDB(t)
+	outgoing := &recordingSender{}
+	mailer.SetSender(outgoing)
+	t.Cleanup(func() { mailer.SetSender(nil) })
+	return NewAuthHandlers(repository.NewGormUserRepository(database.DB)), outgoing
+}
+
+func TestSignupAndLogin(t *testing.T) {
+	h, outgoing := newAuthHandlers(t)
+	ctx := context.Background()
+
+	w := serve(h.Signup, http.MethodPost, "/api/v1/auth/signup",
+		`{"user_name":" Anna ","user_email":" Anna@Example.com ","password":"correct horse"}`, nil, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("signup: status = %d, body %s", w.Code, w.Body)
+	}
+	var signedUp models.AuthResponse
+	decode(t, w, &signedUp)
+	waitForMail(t)
+
+	stored, err := h.Users.FindByEmail(ctx, "anna@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.UserName != "Anna" || stored.Role != models.RoleUser || stored.IsEmailVerified() {
+		t.Errorf("stored %+v, want a trimmed, unverified user", stored)
+	}
+	if !utils.CheckPassword(stored.PasswordHash, "correct horse") {
+		t.Error("password hash not stored")
+	}
+	if got := outgoing.recipients(); len(got) != 1 || got[0] != "anna@example.com" {
+		t.Errorf("verification mail sent to %v", got)
+	}
+
+	w = serve(h.Signup, http.MethodPost, "/api/v1/auth/signup",
+		`{"user_name":"Other","user_email":"ANNA@example.com","password":"correct horse"}`, nil, nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate signup: status = %d, want 409", w.Code)
+	}
+
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`{"user_email":"anna@example.com","password":"correct horse"}`, http.StatusOK},
+		{`{"user_email":" ANNA@example.com","password":"correct horse"}`, http.StatusOK},
+		{`{"user_email":"anna@example.com","password":"wrong horse"}`, http.StatusUnauthorized},
+		{`{"user_email":"nobody@example.com","password":"correct horse"}`, http.StatusUnauthorized},
+	} {
+		if w := serve(h.Login, http.MethodPost, "/api/v1/auth/login", tt.body, nil, nil); w.Code != tt.want {
+			t.Errorf("login with %s: status = %d, want %d", tt.body, w.Code, tt.want)
+		}
+	}
+
+	// Refreshing loads the account through the repository
+	w = serve(h.Refresh, http.MethodPost, "/api/v1/auth/refresh",
+		`{"refresh_token":"`+signedUp.RefreshToken+`"}`, nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: status = %d, body %s", w.Code, w.Body)
+	}
+	var refreshed models.AuthResponse
+	decode(t, w, &refreshed)
+	if refreshed.User.UserID != stored.UserID || refreshed.User.UserEmail != "anna@example.com" {
+		t.Errorf("refresh returned user %+v", refreshed.User)
+	}
+}
+
+var resetTokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+func TestPasswordReset(t *testing.T) {
+	h, outgoing := newAuthHandlers(t)
+	ctx := context.Background()
+
+	w := serve(h.Signup, http.MethodPost, "/api/v1/auth/signup",
+		`{"user_name":"Anna","user_email":"anna@example.com","password":"old password"}`, nil, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("signup: status = %d, body %s", w.Code, w.Body)
+	}
+	waitForMail(t)
+	user, err := h.Users.FindByEmail(ctx, "anna@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.issuePasswordReset(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("reset for an unknown address: %v", err)
+	}
+	if err := h.issuePasswordReset(ctx, "anna@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	outgoing.mu.Lock()
+	match := resetTokenPattern.FindSubmatch(outgoing.sent[len(outgoing.sent)-1].Text)
+	outgoing.mu.Unlock()
+	if match == nil {
+		t.Fatal("reset mail carries no link")
+	}
+	token, _ := url.QueryUnescape(string(match[1]))
+
+	w = serve(h.ResetPassword, http.MethodPost, "/api/v1/auth/password/reset",
+		`{"token":"`+token+`","password":"new password"}`, nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("reset: status = %d, body %s", w.Code, w.Body)
+	}
+
+	if _, err := h.authenticateUser(ctx, "anna@example.com", "old password"); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Errorf("old password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := h.authenticateUser(ctx, "anna@example.com", "new password"); err != nil {
+		t.Errorf("new password: %v", err)
+	}
+
+	var active int64
+	database.DB.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.UserID).Count(&active)
+	if active != 0 {
+		t.Errorf("%d sessions still active after the reset", active)
+	}
+
+	w = serve(h.ResetPassword, http.MethodPost, "/api/v1/auth/password/reset",
+		`{"token":"`+token+`","password":"third password"}`, nil, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("reused token: status = %d, want 400", w.Code)
+	}
+}
+
+func TestTOTPEnrolment(t *testing.T) {
+	h, _ := newAuthHandlers(t)
+	ctx := context.Background()
+
+	hash, _ := utils.HashPassword("correct horse")
+	user := &models.User{UserName: "anna", UserEmail: "anna@example.com", PasswordHash: hash, Role: models.RoleUser}
+	if err := h.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	// reload returns the account as Authenticate would put it in the context
+	reload := func() *models.User {
+		t.Helper()
+		stored, err := h.Users.FindByID(ctx, user.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+
+	w := serve(h.EnrollTOTP, http.MethodPost, "/api/v1/auth/2fa/enroll", "", reload(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enroll: status = %d, body %s", w.Code, w.Body)
+	}
+	var enrollment models.TOTPEnrollment
+	decode(t, w, &enrollment)
+	if stored := reload(); stored.TOTPSecret != enrollment.Secret || stored.TOTPEnabled {
+		t.Fatalf("after enrolment: %+v", stored)
+	}
+
+	code, _ := utils.TOTPCode(enrollment.Secret, utils.TOTPStep(time.Now()))
+	w = serve(h.ConfirmTOTP, http.MethodPost, "/api/v1/auth/2fa/confirm", `{"code":"`+code+`"}`, reload(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("confirm: status = %d, body %s", w.Code, w.Body)
+	}
+	var recovery map[string][]string
+	decode(t, w, &recovery)
+	if stored := reload(); !stored.TOTPEnabled || stored.TOTPLastStep == 0 {
+		t.Fatalf("after confirmation: %+v", stored)
+	}
+	if len(recovery["recovery_codes"]) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes", len(recovery["recovery_codes"]))
+	}
+
+	w = serve(h.DisableTOTP, http.MethodPost, "/api/v1/auth/2fa/disable",
+		`{"password":"correct horse","recovery_code":"`+recovery["recovery_codes"][0]+`"}`, reload(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("disable: status = %d, body %s", w.Code, w.Body)
+	}
+	if stored := reload(); stored.TOTPEnabled || stored.TOTPSecret != "" || stored.TOTPLastStep != 0 {
+		t.Errorf("after disabling: %+v", stored)
+	}
+	var codes int64
+	database.DB.Model(&models.RecoveryCode{}).Where("user_id = ?", user.UserID).Count(&codes)
+	if codes != 0 {
+		t.Errorf("%d recovery codes left after disabling", codes)
+	}
+}
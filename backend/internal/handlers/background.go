@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+)
+
+// background tracks work that outlives the request that started it, such as
+// sending mail, so shutdown can wait for it.
+var background sync.WaitGroup
+
+func runInBackground(task func()) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		task()
+	}()
+}
+
+// WaitForBackgroundTasks blocks until all background work has finished or
+// ctx is done.
+func WaitForBackgroundTasks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -4,14 +4,19 @@ import (
 	"io"
 	"main/internal/models"
 	"main/internal/utils"
+	"main/pkg/mailer"
 	"net/http"
 	"os"
 
 	"github.com/jordan-wright/email"
-	"net/smtp"
 )
 
 func SendEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if settings.SupportAddress == "" {
+		utils.SendErrorResponse(w, "Support email is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		utils.SendErrorResponse(w, "Unable to process form", http.StatusBadRequest)
 		return
@@ -20,9 +25,16 @@ func SendEmailHandler(w http.ResponseWriter, r *http.Request) {
 	from := r.FormValue("email")
 	message := r.FormValue("message")
 
+	if !utils.IsValidEmail(from) {
+		utils.SendErrorResponse(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+
+	// The relay only sends as the configured sender, so the visitor's
+	// address goes into Reply-To.
 	e := email.NewEmail()
-	e.From = from
-	e.To = []string{"kh.abukhassym@gmail.com"}
+	e.ReplyTo = []string{from}
+	e.To = []string{settings.SupportAddress}
 	e.Subject = "Support Request"
 	e.Text = []byte(message)
 
@@ -46,8 +58,7 @@ func SendEmailHandler(w http.ResponseWriter, r *http.Request) {
 		defer os.Remove(attachmentPath)
 	}
 
-	auth := smtp.PlainAuth("", "kh.abukhassym@gmail.com", "bsml lwzy akas ezfm", "smtp.gmail.com")
-	if err := e.Send("smtp.gmail.com:587", auth); err != nil {
+	if err := mailer.Send(r.Context(), e); err != nil {
 		utils.SendErrorResponse(w, "Error sending email", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jordan-wright/email"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/utils"
+	"main/pkg/mailer"
+)
+
+func TestMain(m *testing.M) {
+	utils.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testEnv holds handlers backed by in-memory repositories.
+type testEnv struct {
+	users    *repository.MemoryUserRepository
+	posts    *repository.MemoryPostRepository
+	userAPI  *UserHandlers
+	postAPI  *PostHandlers
+	outgoing *recordingSender
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	users := repository.NewMemoryUserRepository()
+	posts := repository.NewMemoryPostRepository(users)
+	outgoing := &recordingSender{}
+	mailer.SetSender(outgoing)
+	t.Cleanup(func() { mailer.SetSender(nil) })
+	return &testEnv{
+		users:    users,
+		posts:    posts,
+		userAPI:  NewUserHandlers(users),
+		postAPI:  NewPostHandlers(posts),
+		outgoing: outgoing,
+	}
+}
+
+// addUser stores a verified user with role and returns it.
+func (e *testEnv) addUser(t *testing.T, name, role string) *models.User {
+	t.Helper()
+	now := time.Now()
+	user := &models.User{
+		UserName:        name,
+		UserEmail:       strings.ToLower(name) + "@example.com",
+		PasswordHash:    "hash-of-" + name,
+		Role:            role,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := e.users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func (e *testEnv) addPost(t *testing.T, author *models.User, body string) *models.Post {
+	t.Helper()
+	post := &models.Post{UserID: author.UserID, Body: body, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := e.posts.Create(context.Background(), post); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+// recordingSender keeps mail instead of delivering it.
+type recordingSender struct {
+	mu   sync.Mutex
+	sent []*email.Email
+}
+
+func (s *recordingSender) Send(e *email.Email) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, e)
+	return nil
+}
+
+func (s *recordingSender) recipients() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var to []string
+	for _, e := range s.sent {
+		to = append(to, e.To...)
+	}
+	return to
+}
+
+// serve runs handler for a request from caller, which may be nil for an
+// anonymous request, with vars as the route variables.
+func serve(handler http.HandlerFunc, method, target, body string, caller *models.User, vars map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if caller != nil {
+		r = r.WithContext(middleware.ContextWithUser(r.Context(), caller))
+	}
+	if vars != nil {
+		r = mux.SetURLVars(r, vars)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// decode unmarshals the data field of a JSON response into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		t.Fatalf("decode data %s: %v", response.Data, err)
+	}
+}
+
+func waitForMail(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := WaitForBackgroundTasks(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"main/internal/models"
+	"main/internal/utils"
+	"main/pkg/database"
+	"main/pkg/mailer"
+)
+
+// healthCheckTimeout bounds each dependency check so a hung dependency
+// cannot stall the load balancer's probe.
+const healthCheckTimeout = 2 * time.Second
+
+// draining is set once graceful shutdown starts.
+var draining atomic.Bool
+
+// StartDraining makes /readyz report the service as unavailable so load
+// balancers stop routing new requests to it.
+func StartDraining() {
+	draining.Store(true)
+}
+
+type dependencyCheck struct {
+	name     string
+	critical bool
+	check    func(ctx context.Context) error
+}
+
+// The mailer is not critical: a relay outage delays emails but the API keeps
+// working, so it is reported without taking the instance out of rotation.
+var dependencyChecks = []dependencyCheck{
+	{name: "database", critical: true, check: database.Ping},
+	{name: "migrations", critical: true, check: database.CheckMigrations},
+	{name: "mailer", critical: false, check: mailer.Check},
+}
+
+// HealthzHandler reports that the process is alive. It does not touch any
+// dependency.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "ok",
+	})
+}
+
+// ReadyzHandler reports whether the instance should receive traffic, with
+// the result of every dependency check.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	results := runDependencyChecks(r.Context())
+
+	ready := true
+	for _, result := range results {
+		if result.Critical && result.Status != "ok" {
+			ready = false
+		}
+	}
+
+	status, message, code := "success", "ready", http.StatusOK
+	switch {
+	case draining.Load():
+		status, message, code = "error", "shutting down", http.StatusServiceUnavailable
+	case !ready:
+		status, message, code = "error", "not ready", http.StatusServiceUnavailable
+	}
+
+	utils.SendJSONResponse(w, code, models.ResponseData{
+		Status:  status,
+		Message: message,
+		Data:    results,
+	})
+}
+
+func runDependencyChecks(ctx context.Context) map[string]models.DependencyStatus {
+	results := make(map[string]models.DependencyStatus, len(dependencyChecks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, dep := range dependencyChecks {
+		wg.Add(1)
+		go func(dep dependencyCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := dep.check(ctx)
+			result := models.DependencyStatus{
+				Status:    "ok",
+				LatencyMS: time.Since(start).Milliseconds(),
+				Critical:  dep.critical,
+			}
+			if err != nil {
+				result.Status = "error"
+				result.Error = err.Error()
+				utils.LoggerFromContext(ctx).WithError(err).WithField("dependency", dep.name).Warn("Readiness check failed")
+			}
+
+			mu.Lock()
+			results[dep.name] = result
+			mu.Unlock()
+		}(dep)
+	}
+
+	wg.Wait()
+	return results
+}
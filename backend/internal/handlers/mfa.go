@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/utils"
+	"main/pkg/database"
+)
+
+const (
+	totpIssuer        = "SocialPub"
+	loginMFAPurpose   = "login-mfa"
+	loginMFATTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+type secondFactor struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// EnrollTOTP generates a new, not yet active TOTP secret for the caller. It
+// becomes active once confirmed with a valid code.
+func (h *AuthHandlers) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if caller.TOTPEnabled {
+		utils.SendErrorResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not start enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	user := *caller
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	user.UpdatedAt = time.Now().UTC()
+	if err := h.Users.UpdateTOTP(r.Context(), &user); err != nil {
+		utils.SendErrorResponse(w, "Could not start enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Scan the secret with an authenticator app and confirm with a code",
+		Data: models.TOTPEnrollment{
+			Secret:     secret,
+			OTPAuthURI: utils.TOTPURI(totpIssuer, caller.UserEmail, secret),
+		},
+	})
+}
+
+// ConfirmTOTP activates a pending TOTP secret and returns the account's
+// recovery codes. The codes are only ever shown here.
+func (h *AuthHandlers) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if caller.TOTPEnabled {
+		utils.SendErrorResponse(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if caller.TOTPSecret == "" {
+		utils.SendErrorResponse(w, "Start enrolment before confirming", http.StatusBadRequest)
+		return
+	}
+
+	var req secondFactor
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	step, valid := utils.ValidateTOTP(caller.TOTPSecret, req.Code, time.Now(), caller.TOTPLastStep)
+	if !valid {
+		utils.SendErrorResponse(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	// The codes are stored before TOTP is switched on, so a failure between
+	// the two cannot leave the account without a way to recover
+	now := time.Now().UTC()
+	err = database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, caller.UserID, codes, now)
+	})
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	user := *caller
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.UpdatedAt = now
+	if err := h.Users.UpdateTOTP(r.Context(), &user); err != nil {
+		utils.SendErrorResponse(w, "Could not enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Two-factor authentication enabled; store these recovery codes safely",
+		Data:    map[string][]string{"recovery_codes": codes},
+	})
+}
+
+// DisableTOTP turns two-factor authentication off. It requires the password
+// and a current second factor so a stolen session cannot do it.
+func (h *AuthHandlers) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if !caller.TOTPEnabled {
+		utils.SendErrorResponse(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+
+	var req struct {
+		secondFactor
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !utils.CheckPassword(caller.PasswordHash, req.Password) {
+		utils.SendErrorResponse(w, "Invalid password or code", http.StatusUnauthorized)
+		return
+	}
+	if err := h.verifySecondFactor(r.Context(), caller, req.secondFactor); err != nil {
+		sendSecondFactorError(w, err)
+		return
+	}
+
+	user := *caller
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.UpdatedAt = time.Now().UTC()
+	if err := h.Users.UpdateTOTP(r.Context(), &user); err != nil {
+		utils.SendErrorResponse(w, "Could not disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	// Recovery codes are only accepted while TOTP is enabled and are
+	// replaced on the next enrolment, so a failure here leaves none usable
+	err := database.DB.WithContext(r.Context()).Where("user_id = ?", caller.UserID).Delete(&models.RecoveryCode{}).Error
+	if err != nil {
+		utils.LoggerFromContext(r.Context()).WithError(err).Error("Failed to delete recovery codes")
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// LoginMFA completes a login that was answered with an MFA challenge.
+func (h *AuthHandlers) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req struct {
+		secondFactor
+		MFAToken string `json:"mfa_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userFromMFAToken(r.Context(), req.MFAToken)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCredentials) {
+			utils.SendErrorResponse(w, "Invalid or expired login challenge", http.StatusUnauthorized)
+			return
+		}
+		utils.SendErrorResponse(w, "Could not log in", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.verifySecondFactor(r.Context(), user, req.secondFactor); err != nil {
+		sendSecondFactorError(w, err)
+		return
+	}
+
+	auth, err := startSession(r, *user)
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not create session", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Logged in successfully",
+		Data:    auth,
+	})
+}
+
+// newMFAChallenge signs a short-lived token proving user passed the password
+// step. It is bound to the current password hash, so a password reset
+// invalidates outstanding challenges.
+func newMFAChallenge(user models.User) models.MFAChallenge {
+	expiresAt := time.Now().Add(loginMFATTL)
+	payload := fmt.Sprintf("%d:%s", user.UserID, utils.HashToken(user.PasswordHash))
+	return models.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    utils.SignToken(loginMFAPurpose, payload, expiresAt),
+		ExpiresAt:   expiresAt,
+	}
+}
+
+func (h *AuthHandlers) userFromMFAToken(ctx context.Context, token string) (*models.User, error) {
+	payload, err := utils.VerifySignedToken(loginMFAPurpose, token)
+	if err != nil {
+		return nil, utils.ErrInvalidCredentials
+	}
+
+	idStr, fingerprint, found := strings.Cut(payload, ":")
+	userID, convErr := strconv.ParseUint(idStr, 10, 32)
+	if !found || convErr != nil {
+		return nil, utils.ErrInvalidCredentials
+	}
+
+	user, err := h.Users.FindByID(ctx, uint(userID))
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			return nil, utils.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if utils.HashToken(user.PasswordHash) != fingerprint || !user.TOTPEnabled {
+		return nil, utils.ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code
+// and records its use so it cannot be presented again.
+func (h *AuthHandlers) verifySecondFactor(ctx context.Context, user *models.User, factor secondFactor) error {
+	if factor.RecoveryCode != "" {
+		return redeemRecoveryCode(ctx, user.UserID, factor.RecoveryCode)
+	}
+	if factor.Code == "" {
+		return utils.ErrInvalidInput
+	}
+
+	step, valid := utils.ValidateTOTP(user.TOTPSecret, factor.Code, time.Now(), user.TOTPLastStep)
+	if !valid {
+		return utils.ErrInvalidCredentials
+	}
+
+	recorded, err := h.Users.RecordTOTPStep(ctx, user.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		return utils.ErrInvalidCredentials
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+func redeemRecoveryCode(ctx context.Context, userID uint, code string) error {
+	result := database.DB.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(utils.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrInvalidCredentials
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []string, now time.Time) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{
+			UserID:    userID,
+			CodeHash:  utils.HashToken(utils.NormalizeRecoveryCode(code)),
+			CreatedAt: now,
+		}
+	}
+	return tx.Create(&records).Error
+}
+
+func sendSecondFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidInput):
+		utils.SendErrorResponse(w, "A code or recovery code is required", http.StatusBadRequest)
+	case errors.Is(err, utils.ErrInvalidCredentials):
+		utils.SendErrorResponse(w, "Invalid code", http.StatusUnauthorized)
+	default:
+		utils.SendErrorResponse(w, "Could not verify code", http.StatusInternalServerError)
+	}
+}
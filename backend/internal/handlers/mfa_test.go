@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/utils"
+	"main/pkg/database"
+	"main/pkg/database/dbtest"
+)
+
+// useTestDB points database.DB at a fresh SQLite database for one test.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	previous := database.DB
+	database.DB = dbtest.Open(t)
+	t.Cleanup(func() { database.DB = previous })
+}
+
+func newMFAUser(t *testing.T) *models.User {
+	t.Helper()
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{
+		UserName:    "anna",
+		UserEmail:   "anna@example.com",
+		Role:        models.RoleUser,
+		TOTPEnabled: true,
+		TOTPSecret:  secret,
+	}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	useTestDB(t)
+	user := newMFAUser(t)
+	h := NewAuthHandlers(repository.NewGormUserRepository(database.DB))
+	ctx := context.Background()
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceRecoveryCodes(database.DB, user.UserID, codes, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Users may type the code without its dash and in capitals
+	typed := "  " + strings.ToUpper(codes[0][:5]+codes[0][6:]) + " "
+	if err := h.verifySecondFactor(ctx, user, secondFactor{RecoveryCode: typed}); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := h.verifySecondFactor(ctx, user, secondFactor{RecoveryCode: codes[0]}); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Errorf("second use: err = %v, want ErrInvalidCredentials", err)
+	}
+	if err := h.verifySecondFactor(ctx, user, secondFactor{RecoveryCode: codes[1]}); err != nil {
+		t.Errorf("another code: %v", err)
+	}
+	if err := h.verifySecondFactor(ctx, user, secondFactor{RecoveryCode: "aaaaa-aaaaa"}); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Errorf("unknown code: err = %v, want ErrInvalidCredentials", err)
+	}
+
+	// Regenerating replaces the old codes
+	fresh, _ := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err := replaceRecoveryCodes(database.DB, user.UserID, fresh, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.verifySecondFactor(ctx, user, secondFactor{RecoveryCode: codes[2]}); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Errorf("replaced code: err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestTOTPCodeCannotBeReplayed(t *testing.T) {
+	useTestDB(t)
+	user := newMFAUser(t)
+	h := NewAuthHandlers(repository.NewGormUserRepository(database.DB))
+	ctx := context.Background()
+
+	code, err := utils.TOTPCode(user.TOTPSecret, utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.verifySecondFactor(ctx, user, secondFactor{Code: code}); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+
+	// A second request that loaded the user before the first one recorded
+	// its step must still be refused by the conditional update
+	stale := *user
+	stale.TOTPLastStep = 0
+	if err := h.verifySecondFactor(ctx, &stale, secondFactor{Code: code}); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Errorf("replay with a stale user: err = %v, want ErrInvalidCredentials", err)
+	}
+	if err := h.verifySecondFactor(ctx, user, secondFactor{Code: code}); !errors.Is(err, utils.ErrInvalidCredentials) {
+		t.Errorf("replay: err = %v, want ErrInvalidCredentials", err)
+	}
+	if err := h.verifySecondFactor(ctx, user, secondFactor{}); !errors.Is(err, utils.ErrInvalidInput) {
+		t.Errorf("no code: err = %v, want ErrInvalidInput", err)
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+	"main/internal/models"
+	"main/internal/utils"
+	"main/pkg/database"
+	"main/pkg/mailer"
+)
+
+const passwordResetTTL = time.Hour
+
+// ForgotPassword mails a one-time reset link. It answers identically whether
+// or not the address belongs to an account.
+func (h *AuthHandlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req struct {
+		UserEmail string `json:"user_email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !utils.IsValidEmail(req.UserEmail) {
+		utils.SendErrorResponse(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+
+	// Lookup and delivery happen in the background so response timing does
+	// not depend on whether a message was sent.
+	email := normalizeEmail(req.UserEmail)
+	ctx := context.WithoutCancel(r.Context())
+	logger := utils.LoggerFromContext(ctx)
+	runInBackground(func() {
+		if err := h.issuePasswordReset(ctx, email); err != nil {
+			logger.WithError(err).Error("Failed to issue password reset")
+		}
+	})
+
+	utils.SendJSONResponse(w, http.StatusAccepted, models.ResponseData{
+		Status:  "success",
+		Message: "If an account exists for that email, a reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password using a reset token and signs the
+// account out everywhere.
+func (h *AuthHandlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.consumePasswordReset(r.Context(), req.Token, hash); err != nil {
+		if errors.Is(err, utils.ErrInvalidCredentials) {
+			utils.SendErrorResponse(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		utils.SendErrorResponse(w, "Could not reset password", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Password has been reset, please log in again",
+	})
+}
+
+// issuePasswordReset stores a new reset token for the account registered
+// under email, if any, retires its earlier tokens and mails the link.
+func (h *AuthHandlers) issuePasswordReset(ctx context.Context, email string) error {
+	user, err := h.Users.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.UserID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.UserID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: now.Add(passwordResetTTL),
+			CreatedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := settings.PublicBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. To choose a new password, open the link below:\n\n%s\n\nThe link can be used once and expires in %d minutes. If you did not ask for this, you can ignore this message.\n",
+		user.UserName, link, int(passwordResetTTL.Minutes()))
+
+	return mailer.SendText(ctx, user.UserEmail, "Reset your password", body)
+}
+
+// consumePasswordReset redeems token, revokes all of the account's sessions
+// and replaces its password hash. The hash is saved last, so a failure leaves
+// the old password signed out everywhere rather than the new one alongside
+// sessions opened with the old.
+func (h *AuthHandlers) consumePasswordReset(ctx context.Context, token, passwordHash string) error {
+	now := time.Now().UTC()
+
+	var userID uint
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reset models.PasswordResetToken
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(token), now).
+			First(&reset).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.ErrInvalidCredentials
+			}
+			return err
+		}
+
+		claim := tx.Model(&models.PasswordResetToken{}).
+			Where("password_reset_token_id = ? AND used_at IS NULL", reset.PasswordResetTokenID).
+			Update("used_at", now)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return utils.ErrInvalidCredentials
+		}
+
+		userID = reset.UserID
+		return revokeUserSessions(tx, reset.UserID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.Users.UpdatePassword(ctx, &models.User{UserID: userID, PasswordHash: passwordHash, UpdatedAt: now})
+}
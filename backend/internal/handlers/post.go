@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"main/internal/listquery"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/repository"
+	"main/internal/utils"
+)
+
+const postsPerPage = 10
+
+// postFields are the fields GET /posts can filter by.
+var postFields = listquery.NewRegistry(
+	listquery.Field{Name: "id", Aliases: []string{"post_id"}, Column: "post_id", Type: listquery.Number,
+		Operators: numberOperators},
+	listquery.Field{Name: "author_id", Aliases: []string{"user_id"}, Column: "user_id", Type: listquery.Number,
+		Operators: numberOperators},
+	listquery.Field{Name: "body", Column: "body", Type: listquery.String,
+		Operators: []listquery.Operator{listquery.Contains, listquery.StartsWith, listquery.EndsWith}},
+	listquery.Field{Name: "created_at", Aliases: []string{"date"}, Column: "created_at", Type: listquery.Time,
+		Operators: timeOperators},
+	listquery.Field{Name: "updated_at", Column: "updated_at", Type: listquery.Time,
+		Operators: timeOperators},
+)
+
+// PostHandlers serves the post endpoints from Posts.
+type PostHandlers struct {
+	Posts repository.PostRepository
+}
+
+func NewPostHandlers(posts repository.PostRepository) *PostHandlers {
+	return &PostHandlers{Posts: posts}
+}
+
+// Collection serves the /api/posts collection.
+func (h *PostHandlers) Collection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		middleware.Authenticate(middleware.RequireVerifiedEmail(h.Create))(w, r)
+	default:
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Item serves a single post at /api/posts/{id}.
+func (h *PostHandlers) Item(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Get(w, r)
+	case http.MethodPut, http.MethodPatch:
+		middleware.Authenticate(h.Update)(w, r)
+	case http.MethodDelete:
+		middleware.Authenticate(h.Delete)(w, r)
+	default:
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *PostHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var post models.Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	post.PostID = 0
+	post.UserID = caller.UserID
+	post.Author = nil
+	post.Body = strings.TrimSpace(post.Body)
+
+	if err := utils.ValidatePost(post); err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.save(r.Context(), &post); err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			utils.SendErrorResponse(w, "Author not found", http.StatusBadRequest)
+			return
+		}
+		utils.SendErrorResponse(w, "Could not create post", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, models.ResponseData{
+		Status:  "success",
+		Message: "Post created successfully",
+		Data:    post.Response(),
+	})
+}
+
+func (h *PostHandlers) List(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	filter, err := postFields.ParseFilter(r.URL.Query())
+	if err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := repository.PostListOptions{
+		Filter: filter,
+		Offset: (page - 1) * postsPerPage,
+		Limit:  postsPerPage,
+	}
+	if authorStr := r.URL.Query().Get("user_id"); authorStr != "" {
+		authorID, err := strconv.ParseUint(authorStr, 10, 32)
+		if err != nil {
+			utils.SendErrorResponse(w, "Invalid user_id format", http.StatusBadRequest)
+			return
+		}
+		opts.AuthorID = uint(authorID)
+	}
+
+	posts, totalItems, err := h.Posts.List(r.Context(), opts)
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not retrieve posts", http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(totalItems) / float64(postsPerPage)))
+
+	data := make([]models.PostResponse, len(posts))
+	for i, post := range posts {
+		data[i] = post.Response()
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.PaginatedResponse{
+		Status:      "success",
+		Message:     "Posts retrieved successfully",
+		Data:        data,
+		TotalItems:  totalItems,
+		TotalPages:  totalPages,
+		CurrentPage: page,
+	})
+}
+
+func (h *PostHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	postID, err := parsePostID(r)
+	if err != nil {
+		utils.SendErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.Posts.FindByID(r.Context(), postID)
+	if err != nil {
+		sendPostLookupError(w, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Post retrieved successfully",
+		Data:    post.Response(),
+	})
+}
+
+func (h *PostHandlers) Update(w http.ResponseWriter, r *http.Request) {
+	postID, err := parsePostID(r)
+	if err != nil {
+		utils.SendErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var updateData struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.Posts.FindByID(r.Context(), postID)
+	if err != nil {
+		sendPostLookupError(w, err)
+		return
+	}
+
+	if post.UserID != caller.UserID {
+		utils.SendErrorResponse(w, "Only the author can edit this post", http.StatusForbidden)
+		return
+	}
+
+	post.Body = strings.TrimSpace(updateData.Body)
+	if err := utils.ValidatePost(*post); err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	post.UpdatedAt = time.Now().UTC()
+
+	if err := h.Posts.UpdateBody(r.Context(), post); err != nil {
+		utils.SendErrorResponse(w, "Could not update post", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Post updated successfully",
+		Data:    post.Response(),
+	})
+}
+
+func (h *PostHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	postID, err := parsePostID(r)
+	if err != nil {
+		utils.SendErrorResponse(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	post, err := h.Posts.FindByID(r.Context(), postID)
+	if err != nil {
+		sendPostLookupError(w, err)
+		return
+	}
+
+	if post.UserID != caller.UserID && !middleware.Can(caller, middleware.PermModeratePosts) {
+		utils.SendErrorResponse(w, "Only the author or a moderator can delete this post", http.StatusForbidden)
+		return
+	}
+
+	if err := h.Posts.Delete(r.Context(), post.PostID); err != nil {
+		utils.SendErrorResponse(w, "Could not delete post", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Post deleted successfully",
+	})
+}
+
+// LegacyCreate serves the deprecated /post endpoint, which takes the post
+// body as {"message": ...}.
+func (h *PostHandlers) LegacyCreate(w http.ResponseWriter, r *http.Request) {
+	// Allow only POST method
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		response := ResponseData{Status: "fail", Message: "Only POST method is allowed"}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Set response header
+	w.Header().Set("Content-Type", "application/json")
+
+	// Limit request body size
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	// Check if request body is empty
+	if r.Body == http.NoBody {
+		w.WriteHeader(http.StatusBadRequest)
+		response := ResponseData{Status: "fail", Message: "Request body cannot be empty"}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Parse JSON body and detect unexpected fields
+	var requestData struct {
+		Message *string `json:"message"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields() // Disallow extra/unknown fields
+	err := decoder.Decode(&requestData)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		message := "Invalid JSON or unexpected fields"
+		if errors.As(err, &typeErr) && typeErr.Field == "message" {
+			message = "Message field must be a string"
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		response := ResponseData{Status: "fail", Message: message}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Check "message" key
+	if requestData.Message == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response := ResponseData{Status: "fail", Message: "Message field is required"}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		response := ResponseData{Status: "fail", Message: "Authentication required"}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Validate and persist the post
+	post := models.Post{UserID: caller.UserID, Body: strings.TrimSpace(*requestData.Message)}
+	if err := utils.ValidatePost(post); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		response := ResponseData{Status: "fail", Message: err.Error()}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	logger := utils.LoggerFromContext(r.Context()).WithField("handler", "post")
+
+	if err := h.save(r.Context(), &post); err != nil {
+		logger.WithError(err).Error("Failed to save post")
+		status, message := http.StatusInternalServerError, "Could not save post"
+		if errors.Is(err, utils.ErrUserNotFound) {
+			status, message = http.StatusBadRequest, "Author not found"
+		}
+		w.WriteHeader(status)
+		response := ResponseData{Status: "fail", Message: message}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	logger.WithField("post_id", post.PostID).Info("Post created")
+
+	// Send success response
+	w.WriteHeader(http.StatusCreated)
+	response := models.ResponseData{Status: "success", Message: "Post created successfully", Data: post.Response()}
+	json.NewEncoder(w).Encode(response)
+}
+
+// save stamps and inserts a new post. It fails with utils.ErrUserNotFound
+// when the author does not exist.
+func (h *PostHandlers) save(ctx context.Context, post *models.Post) error {
+	now := time.Now().UTC()
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	return h.Posts.Create(ctx, post)
+}
+
+func sendPostLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, utils.ErrPostNotFound) {
+		utils.SendErrorResponse(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	utils.SendErrorResponse(w, "Could not retrieve post", http.StatusInternalServerError)
+}
+
+func parsePostID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil || id == 0 {
+		return 0, utils.ErrInvalidInput
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"main/internal/models"
+)
+
+func TestPostCreate(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+
+	w := serve(env.postAPI.Create, http.MethodPost, "/api/v1/posts", `{"user_id":99,"body":"  hello  "}`, bob, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var post models.PostResponse
+	decode(t, w, &post)
+	if post.UserID != bob.UserID || post.Body != "hello" {
+		t.Errorf("created %+v, want the caller's post with a trimmed body", post)
+	}
+
+	w = serve(env.postAPI.Create, http.MethodPost, "/api/v1/posts", `{"body":"   "}`, bob, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("blank body: status = %d, want 400", w.Code)
+	}
+}
+
+func TestPostResponsesHideAuthorDetails(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+	post := env.addPost(t, bob, "hello")
+	vars := map[string]string{"id": strconv.Itoa(int(post.PostID))}
+
+	responses := map[string]string{
+		"list":   serve(env.postAPI.List, http.MethodGet, "/api/v1/posts", "", nil, nil).Body.String(),
+		"get":    serve(env.postAPI.Get, http.MethodGet, "/api/v1/posts/x", "", nil, vars).Body.String(),
+		"create": serve(env.postAPI.Create, http.MethodPost, "/api/v1/posts", `{"body":"hi"}`, bob, nil).Body.String(),
+		"legacy": serve(env.postAPI.LegacyCreate, http.MethodPost, "/post", `{"message":"hi"}`, bob, nil).Body.String(),
+	}
+	for name, body := range responses {
+		if !strings.Contains(body, `"user_name":"Bob"`) {
+			t.Errorf("%s: author name missing: %s", name, body)
+		}
+		for _, private := range []string{"user_email", "role", "email_verified_at", "totp_enabled"} {
+			if strings.Contains(body, private) {
+				t.Errorf("%s: response exposes %s: %s", name, private, body)
+			}
+		}
+	}
+}
+
+func TestPostList(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+	carol := env.addUser(t, "Carol", models.RoleUser)
+	env.addPost(t, bob, "Hello world")
+	env.addPost(t, carol, "hello there")
+	env.addPost(t, bob, "goodbye")
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantBodies []string
+	}{
+		{"", http.StatusOK, []string{"goodbye", "hello there", "Hello world"}},
+		{"user_id=" + strconv.Itoa(int(bob.UserID)), http.StatusOK, []string{"goodbye", "Hello world"}},
+		{"filter[body][contains]=hello", http.StatusOK, []string{"hello there", "Hello world"}},
+		{"filter[body][contains:cs]=Hello", http.StatusOK, []string{"Hello world"}},
+		{"filter[author_id][in]=" + strconv.Itoa(int(carol.UserID)), http.StatusOK, []string{"hello there"}},
+		{"filter[body][equals]=goodbye", http.StatusBadRequest, nil},
+		{"user_id=abc", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(env.postAPI.List, http.MethodGet, "/api/v1/posts?"+tt.query, "", nil, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantBodies == nil {
+				return
+			}
+			var posts []models.PostResponse
+			decode(t, w, &posts)
+			var bodies []string
+			for _, p := range posts {
+				bodies = append(bodies, p.Body)
+			}
+			if !slices.Equal(bodies, tt.wantBodies) {
+				t.Errorf("got %v, want %v", bodies, tt.wantBodies)
+			}
+		})
+	}
+}
+
+func TestPostUpdateAndDelete(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+	carol := env.addUser(t, "Carol", models.RoleUser)
+	moderator := env.addUser(t, "Mod", models.RoleModerator)
+	post := env.addPost(t, bob, "original")
+	vars := map[string]string{"id": strconv.Itoa(int(post.PostID))}
+
+	w := serve(env.postAPI.Update, http.MethodPut, "/", `{"body":"defaced"}`, carol, vars)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("update by another user: status = %d, want 403", w.Code)
+	}
+	w = serve(env.postAPI.Update, http.MethodPut, "/", `{"body":"edited"}`, bob, vars)
+	if w.Code != http.StatusOK {
+		t.Errorf("update by author: status = %d, body %s", w.Code, w.Body)
+	}
+	stored, err := env.posts.FindByID(context.Background(), post.PostID)
+	if err != nil || stored.Body != "edited" {
+		t.Errorf("stored post = %+v, %v", stored, err)
+	}
+
+	w = serve(env.postAPI.Delete, http.MethodDelete, "/", "", carol, vars)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("delete by another user: status = %d, want 403", w.Code)
+	}
+	w = serve(env.postAPI.Delete, http.MethodDelete, "/", "", moderator, vars)
+	if w.Code != http.StatusOK {
+		t.Errorf("delete by moderator: status = %d, body %s", w.Code, w.Body)
+	}
+	w = serve(env.postAPI.Get, http.MethodGet, "/", "", nil, vars)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get deleted post: status = %d, want 404", w.Code)
+	}
+}
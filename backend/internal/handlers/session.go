@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/utils"
+	"main/pkg/database"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Refresh exchanges a refresh token for a new access/refresh pair.
+func (h *AuthHandlers) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		utils.SendErrorResponse(w, "Invalid or missing refresh token", http.StatusBadRequest)
+		return
+	}
+
+	auth, err := h.rotateRefreshToken(r, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCredentials) {
+			utils.SendErrorResponse(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		utils.SendErrorResponse(w, "Could not refresh session", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Session refreshed successfully",
+		Data:    auth,
+	})
+}
+
+// Logout revokes the session the caller authenticated with.
+func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, ok := middleware.SessionFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := revokeSession(r.Context(), session.SessionID); err != nil {
+		utils.SendErrorResponse(w, "Could not log out", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Logged out successfully",
+	})
+}
+
+// Sessions lists the caller's active sessions.
+func (h *AuthHandlers) Sessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	current, ok := middleware.SessionFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var sessions []models.Session
+	err := database.DB.WithContext(r.Context()).
+		Where("user_id = ? AND revoked_at IS NULL AND refresh_expires_at > ?", current.UserID, time.Now().UTC()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error
+	if err != nil {
+		utils.SendErrorResponse(w, "Could not retrieve sessions", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].SessionID == current.SessionID
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+// RevokeSession signs out one of the caller's devices.
+func (h *AuthHandlers) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil || sessionID == 0 {
+		utils.SendErrorResponse(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.DB.WithContext(r.Context()).Model(&models.Session{}).
+		Where("session_id = ? AND user_id = ? AND revoked_at IS NULL", uint(sessionID), caller.UserID).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		utils.SendErrorResponse(w, "Could not revoke session", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.SendErrorResponse(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Session revoked successfully",
+	})
+}
+
+// startSession opens a new session for user and issues its first token pair.
+// Only token hashes are persisted; the plaintext is returned to the client once.
+func startSession(r *http.Request, user models.User) (*models.AuthResponse, error) {
+	accessToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := models.Session{
+		UserID:           user.UserID,
+		TokenHash:        utils.HashToken(accessToken),
+		UserAgent:        r.UserAgent(),
+		IPAddress:        utils.ClientIP(r),
+		ExpiresAt:        now.Add(accessTokenTTL),
+		RefreshExpiresAt: now.Add(refreshTokenTTL),
+		LastSeenAt:       now,
+		CreatedAt:        now,
+	}
+
+	err = database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{
+			SessionID: session.SessionID,
+			TokenHash: utils.HashToken(refreshToken),
+			ExpiresAt: session.RefreshExpiresAt,
+			CreatedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        session.ExpiresAt,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+		User:             user,
+	}, nil
+}
+
+// rotateRefreshToken consumes presented and issues the session's next token
+// pair. Presenting an already used token revokes the whole session, since
+// either the client or an attacker is holding a stolen copy.
+func (h *AuthHandlers) rotateRefreshToken(r *http.Request, presented string) (*models.AuthResponse, error) {
+	accessToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var session models.Session
+	var reusedSessionID uint
+
+	err = database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		err := tx.Preload("Session").
+			Where("token_hash = ?", utils.HashToken(presented)).
+			First(&current).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.ErrInvalidCredentials
+			}
+			return err
+		}
+		if current.Session == nil || current.Session.RevokedAt != nil {
+			return utils.ErrInvalidCredentials
+		}
+		if current.UsedAt != nil {
+			reusedSessionID = current.SessionID
+			return utils.ErrInvalidCredentials
+		}
+		if !current.ExpiresAt.After(now) {
+			return utils.ErrInvalidCredentials
+		}
+
+		// The used_at guard makes concurrent refreshes with the same token
+		// race for a single winner; the loser is treated as reuse.
+		claim := tx.Model(&models.RefreshToken{}).
+			Where("refresh_token_id = ? AND used_at IS NULL", current.RefreshTokenID).
+			Update("used_at", now)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			reusedSessionID = current.SessionID
+			return utils.ErrInvalidCredentials
+		}
+
+		session = *current.Session
+		session.TokenHash = utils.HashToken(accessToken)
+		session.ExpiresAt = now.Add(accessTokenTTL)
+		session.RefreshExpiresAt = now.Add(refreshTokenTTL)
+		session.LastSeenAt = now
+		session.UserAgent = r.UserAgent()
+		session.IPAddress = utils.ClientIP(r)
+
+		err = tx.Model(&models.Session{}).
+			Where("session_id = ?", session.SessionID).
+			Updates(map[string]interface{}{
+				"token_hash":         session.TokenHash,
+				"expires_at":         session.ExpiresAt,
+				"refresh_expires_at": session.RefreshExpiresAt,
+				"last_seen_at":       session.LastSeenAt,
+				"user_agent":         session.UserAgent,
+				"ip_address":         session.IPAddress,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.RefreshToken{
+			SessionID: session.SessionID,
+			TokenHash: utils.HashToken(refreshToken),
+			ExpiresAt: session.RefreshExpiresAt,
+			CreatedAt: now,
+		}).Error
+	})
+
+	if reusedSessionID != 0 {
+		if revokeErr := revokeSession(r.Context(), reusedSessionID); revokeErr != nil {
+			return nil, revokeErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.Users.FindByID(r.Context(), session.UserID)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			return nil, utils.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        session.ExpiresAt,
+		RefreshExpiresAt: session.RefreshExpiresAt,
+		User:             *user,
+	}, nil
+}
+
+func revokeSession(ctx context.Context, sessionID uint) error {
+	return database.DB.WithContext(ctx).Model(&models.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// revokeUserSessions signs userID out of every device.
+func revokeUserSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"strings"
+
+	"main/internal/config"
+)
+
+// settings holds the parts of the configuration handlers need at request time.
+var settings = struct {
+	// PublicBaseURL is the externally reachable origin used in emailed links.
+	PublicBaseURL string
+	// SupportAddress receives contact form messages.
+	SupportAddress string
+}{
+	PublicBaseURL: "http://localhost:8080",
+}
+
+// Configure applies cfg to the handlers. It must be called before serving
+// requests.
+func Configure(cfg config.Config) {
+	settings.PublicBaseURL = strings.TrimRight(cfg.Server.PublicBaseURL, "/")
+	settings.SupportAddress = cfg.Mail.SupportAddress
+}
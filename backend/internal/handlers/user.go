@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"gorm.io/gorm"
-	"log"
+	"github.com/gorilla/mux"
+	"main/internal/listquery"
+	"main/internal/middleware"
 	"main/internal/models"
+	"main/internal/repository"
 	"main/internal/utils"
-	"main/pkg/database"
 	"math"
 	"net/http"
 	"strconv"
@@ -25,89 +27,7 @@ type ResponseData struct {
 	Message string `json:"message"`
 }
 
-func PostHandler(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	// Handle preflight requests
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	// Allow only POST method
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		response := ResponseData{Status: "fail", Message: "Only POST method is allowed"}
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	// Set response header
-	w.Header().Set("Content-Type", "application/json")
-
-	// Limit request body size
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
-
-	// Check if request body is empty
-	if r.Body == http.NoBody {
-		w.WriteHeader(http.StatusBadRequest)
-		response := ResponseData{Status: "fail", Message: "Request body cannot be empty"}
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	// Parse JSON body and detect unexpected fields
-	var requestData map[string]interface{}
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields() // Disallow extra/unknown fields
-	err := decoder.Decode(&requestData)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := ResponseData{Status: "fail", Message: "Invalid JSON or unexpected fields"}
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	// Check "message" key
-	message, ok := requestData["message"]
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		response := ResponseData{Status: "fail", Message: "Message field is required"}
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	// Validate "message" value
-	messageStr, isString := message.(string)
-	if !isString {
-		w.WriteHeader(http.StatusBadRequest)
-		response := ResponseData{Status: "fail", Message: "Message field must be a string"}
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-
-	fmt.Printf("Received message: %s\n", messageStr)
-
-	// Send success response
-	response := ResponseData{Status: "success", Message: "Data successfully received"}
-	json.NewEncoder(w).Encode(response)
-}
-
 func GetHandler(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	// Handle preflight requests
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
 	// Allow only GET method
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
@@ -124,13 +44,11 @@ func GetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	logger := utils.LoggerFromContext(r.Context()).WithField("handler", "get")
+
 	// Parse and log query parameters
 	queryParams := r.URL.Query()
-	if len(queryParams) == 0 {
-		fmt.Println("No query parameters provided")
-	} else {
-		fmt.Println("Query Parameters:", queryParams)
-	}
+	logger.WithField("param_count", len(queryParams)).Debug("Query parameters received")
 
 	// Validate "message" query parameter
 	msg := queryParams.Get("message")
@@ -149,58 +67,104 @@ func GetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Println("Query parameter 'message':", msg)
+	logger.WithField("message_length", len(msg)).Info("GET message received")
 
 	// Send success response
 	response := ResponseData{Status: "success", Message: "GET request received"}
 	err := json.NewEncoder(w).Encode(response)
 	if err != nil {
-		log.Println("Error encoding JSON response:", err)
+		logger.WithError(err).Error("Failed to encode JSON response")
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
-func UserHandler(w http.ResponseWriter, r *http.Request) {
+// UserHandlers serves the user endpoints from Users.
+type UserHandlers struct {
+	Users repository.UserRepository
+}
+
+func NewUserHandlers(users repository.UserRepository) *UserHandlers {
+	return &UserHandlers{Users: users}
+}
+
+var stringOperators = []listquery.Operator{listquery.Equals, listquery.In, listquery.Contains, listquery.StartsWith, listquery.EndsWith}
+var numberOperators = []listquery.Operator{listquery.Equals, listquery.In, listquery.Between}
+var timeOperators = []listquery.Operator{listquery.Equals, listquery.Before, listquery.After, listquery.Between}
+
+// userFields are the fields GET /users can filter and sort by. The aliases
+// are the column names sort_field used to take and the old "date" filter.
+// privateUserFields require PermViewUserDetails.
+var userFields = listquery.NewRegistry(
+	listquery.Field{Name: "id", Aliases: []string{"user_id"}, Column: "user_id", Type: listquery.Number,
+		Operators: numberOperators, Sortable: true},
+	listquery.Field{Name: "name", Aliases: []string{"user_name"}, Column: "user_name", Type: listquery.String,
+		Operators: stringOperators, Sortable: true},
+	listquery.Field{Name: "email", Aliases: []string{"user_email"}, Column: "user_email", Type: listquery.String,
+		Operators: stringOperators, Sortable: true},
+	listquery.Field{Name: "role", Column: "role", Type: listquery.String,
+		Operators: []listquery.Operator{listquery.Equals, listquery.In}, Sortable: true},
+	listquery.Field{Name: "created_at", Aliases: []string{"date"}, Column: "created_at", Type: listquery.Time,
+		Operators: timeOperators, Sortable: true},
+	listquery.Field{Name: "updated_at", Column: "updated_at", Type: listquery.Time,
+		Operators: timeOperators, Sortable: true},
+	listquery.Field{Name: "email_verified_at", Column: "email_verified_at", Type: listquery.Time,
+		Operators: append(timeOperators, listquery.IsNull), Sortable: true},
+)
+
+var privateUserFields = []string{"email", "email_verified_at"}
+
+// Collection serves the deprecated /users collection.
+func (h *UserHandlers) Collection(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		CreateUser(w, r)
+		middleware.Authorize(middleware.PermManageUsers, h.Create)(w, r)
 	case http.MethodGet:
-		GetAllUsers(w, r)
+		h.List(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-func CreateUser(w http.ResponseWriter, r *http.Request) {
-	logger := utils.Log.WithField("handler", "createUser")
-
-	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		utils.HandleError(w, utils.ErrInvalidInput, http.StatusBadRequest, logger)
+func (h *UserHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	// Only the name and address come from the client; the ID, role, MFA and
+	// verification state are never taken from the request
+	var createData struct {
+		UserName  string `json:"user_name"`
+		UserEmail string `json:"user_email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&createData); err != nil {
+		utils.HandleError(w, r, utils.ErrInvalidInput, http.StatusBadRequest)
 		return
 	}
 
-	// Set created_at and updated_at timestamps
-	now := time.Now()
-	user.CreatedAt = now
-	user.UpdatedAt = now
+	// Store names and addresses the way registration and login expect them
+	now := time.Now().UTC()
+	user := models.User{
+		UserName:  strings.TrimSpace(createData.UserName),
+		UserEmail: normalizeEmail(createData.UserEmail),
+		Role:      models.RoleUser,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
 
 	// Validate user fields
 	if err := utils.ValidateUser(user); err != nil {
-		utils.HandleError(w, err, http.StatusBadRequest, logger)
+		utils.HandleError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	// Save user to database
-	if err := database.DB.Create(&user).Error; err != nil {
-		if utils.IsDuplicateEmailError(err) {
-			utils.HandleError(w, utils.ErrDuplicateEmail, http.StatusConflict, logger)
+	if err := h.Users.Create(r.Context(), &user); err != nil {
+		if errors.Is(err, utils.ErrDuplicateEmail) {
+			utils.HandleError(w, r, utils.ErrDuplicateEmail, http.StatusConflict)
 			return
 		}
-		utils.HandleError(w, utils.ErrDatabaseOperation, http.StatusInternalServerError, logger)
+		utils.HandleError(w, r, fmt.Errorf("%w: %v", utils.ErrDatabaseOperation, err), http.StatusInternalServerError)
 		return
 	}
 
+	sendVerificationEmailAsync(r.Context(), user)
+
 	// Send response
 	utils.SendJSONResponse(w, http.StatusCreated, models.ResponseData{
 		Status:  "success",
@@ -208,7 +172,8 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		Data:    user,
 	})
 }
-func GetAllUsers(w http.ResponseWriter, r *http.Request) {
+
+func (h *UserHandlers) List(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	filterField := r.URL.Query().Get("filter_field")
 	filterValue := r.URL.Query().Get("filter_value")
@@ -221,39 +186,80 @@ func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	}
 	itemsPerPage := 5
 
-	query := database.DB.Model(&models.User{})
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	fullRecords := middleware.Can(caller, middleware.PermViewUserDetails)
 
+	filter, err := userFields.ParseFilter(r.URL.Query())
+	if err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := repository.UserListOptions{
+		Filter: filter,
+		Offset: (page - 1) * itemsPerPage,
+		Limit:  itemsPerPage,
+	}
+	// The single filter_field/filter_operator/filter_value triple predates
+	// filter[...] and is combined with it
 	if filterValue != "" {
-		query = applyFilter(query, filterField, filterValue, filterOperator)
+		condition, err := userFields.Filter(filterField, filterOperator, filterValue)
+		if err != nil {
+			utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Filter = listquery.And(opts.Filter, condition)
+	}
+	if sortField != "" {
+		sort, err := userFields.Sort(sortField, sortDir)
+		if err != nil {
+			utils.SendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Sort = append(opts.Sort, sort)
 	}
 
-	var totalItems int64
-	query.Count(&totalItems)
-
-	query = applySorting(query, sortField, sortDir)
-
-	offset := (page - 1) * itemsPerPage
-	query = query.Offset(offset).Limit(itemsPerPage)
+	// Filtering or sorting on private fields would let callers probe them
+	if !fullRecords {
+		for _, name := range privateUserFields {
+			if usesField(opts, name) {
+				utils.SendErrorResponse(w, "You do not have permission to filter by "+name, http.StatusForbidden)
+				return
+			}
+		}
+	}
 
-	var users []models.User
-	if err := query.Find(&users).Error; err != nil {
+	users, totalItems, err := h.Users.List(r.Context(), opts)
+	if err != nil {
 		utils.SendErrorResponse(w, "Could not retrieve users", http.StatusInternalServerError)
 		return
 	}
 
 	totalPages := int(math.Ceil(float64(totalItems) / float64(itemsPerPage)))
 
+	var data interface{} = users
+	if !fullRecords {
+		public := make([]models.PublicUser, len(users))
+		for i, user := range users {
+			public[i] = user.Public()
+		}
+		data = public
+	}
+
 	utils.SendJSONResponse(w, http.StatusOK, models.PaginatedResponse{
 		Status:      "success",
 		Message:     "Users retrieved successfully",
-		Data:        users,
+		Data:        data,
 		TotalItems:  totalItems,
 		TotalPages:  totalPages,
 		CurrentPage: page,
 	})
 }
 
-func UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandlers) Update(w http.ResponseWriter, r *http.Request) {
 	var updateData struct {
 		UserID    uint   `json:"user_id"`
 		UserName  string `json:"user_name,omitempty"`
@@ -264,31 +270,64 @@ func UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if id, found, err := requestedUserID(r); err != nil {
+		utils.SendErrorResponse(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	} else if found {
+		updateData.UserID = id
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if updateData.UserID == 0 {
+		updateData.UserID = caller.UserID
+	}
+	if !canManageUser(caller, updateData.UserID, middleware.PermManageUsers) {
+		utils.SendErrorResponse(w, "You can only update your own account", http.StatusForbidden)
+		return
+	}
 
-	var user models.User
-	if err := database.DB.First(&user, updateData.UserID).Error; err != nil {
+	user, err := h.Users.FindByID(r.Context(), updateData.UserID)
+	if err != nil {
 		utils.SendErrorResponse(w, "User not found", http.StatusNotFound)
 		return
 	}
 
 	if updateData.UserName != "" {
-		user.UserName = updateData.UserName
+		user.UserName = strings.TrimSpace(updateData.UserName)
 	}
+	emailChanged := false
 	if updateData.UserEmail != "" {
-		if !utils.IsValidEmail(updateData.UserEmail) {
-			utils.SendErrorResponse(w, "Invalid email format", http.StatusBadRequest)
-			return
+		// A new address has to be verified again
+		if email := normalizeEmail(updateData.UserEmail); email != user.UserEmail {
+			user.UserEmail = email
+			user.EmailVerifiedAt = nil
+			emailChanged = true
 		}
-		user.UserEmail = updateData.UserEmail
+	}
+	if err := utils.ValidateUser(*user); err != nil {
+		utils.HandleError(w, r, err, http.StatusBadRequest)
+		return
 	}
 
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = time.Now().UTC()
 
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := h.Users.Update(r.Context(), user); err != nil {
+		if errors.Is(err, utils.ErrDuplicateEmail) {
+			utils.HandleError(w, r, utils.ErrDuplicateEmail, http.StatusConflict)
+			return
+		}
 		utils.SendErrorResponse(w, "Could not update user", http.StatusInternalServerError)
 		return
 	}
 
+	if emailChanged {
+		sendVerificationEmailAsync(r.Context(), *user)
+	}
+
 	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
 		Status:  "success",
 		Message: "User updated successfully",
@@ -296,16 +335,10 @@ func UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
-	var deleteID uint
-	var err error
-
-	idStr := r.URL.Query().Get("id")
-	if idStr != "" {
-		var id uint64
-		id, err = strconv.ParseUint(idStr, 10, 32)
-		deleteID = uint(id)
-	} else {
+func (h *UserHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	deleteID, found, err := requestedUserID(r)
+	if !found && err == nil {
+		// The deprecated /user/delete also accepts {"user_id": ...}
 		var requestBody map[string]uint
 		err = json.NewDecoder(r.Body).Decode(&requestBody)
 		if err == nil {
@@ -318,14 +351,22 @@ func DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := database.DB.Delete(&models.User{}, deleteID)
-	if result.Error != nil {
-		utils.SendErrorResponse(w, "Could not delete user", http.StatusInternalServerError)
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if !canManageUser(caller, deleteID, middleware.PermDeleteUsers) {
+		utils.SendErrorResponse(w, "You can only delete your own account", http.StatusForbidden)
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		utils.SendErrorResponse(w, "No user found with the given ID", http.StatusNotFound)
+	if err := h.Users.Delete(r.Context(), deleteID); err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			utils.SendErrorResponse(w, "No user found with the given ID", http.StatusNotFound)
+			return
+		}
+		utils.SendErrorResponse(w, "Could not delete user", http.StatusInternalServerError)
 		return
 	}
 
@@ -335,82 +376,133 @@ func DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func GetUserHandler(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
+func (h *UserHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	id, found, err := requestedUserID(r)
+	if !found {
 		utils.SendErrorResponse(w, "Missing 'id' parameter", http.StatusBadRequest)
 		return
 	}
-
-	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
 		utils.SendErrorResponse(w, "Invalid ID format", http.StatusBadRequest)
 		return
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, uint(id)).Error; err != nil {
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.Users.FindByID(r.Context(), id)
+	if err != nil {
 		utils.SendErrorResponse(w, "User not found", http.StatusNotFound)
 		return
 	}
 
+	var data interface{} = user
+	if caller.UserID != user.UserID && !middleware.Can(caller, middleware.PermViewUserDetails) {
+		data = user.Public()
+	}
+
 	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
 		Status:  "success",
 		Message: "User retrieved successfully",
-		Data:    user,
+		Data:    data,
 	})
 }
 
-// Helper functions for filtering and sorting
-func applyFilter(query *gorm.DB, field, value, operator string) *gorm.DB {
-	switch field {
-	case "name":
-		return applyStringFilter(query, "user_name", value, operator)
-	case "email":
-		return applyStringFilter(query, "user_email", value, operator)
-	case "date":
-		return applyDateFilter(query, "created_at", value, operator)
-	default:
-		return query
+func (h *UserHandlers) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	var roleData struct {
+		UserID uint   `json:"user_id"`
+		Role   string `json:"role"`
 	}
-}
 
-func applyStringFilter(query *gorm.DB, field, value, operator string) *gorm.DB {
-	switch operator {
-	case "contains":
-		return query.Where(field+" ILIKE ?", "%"+value+"%")
-	case "equals":
-		return query.Where(field+" = ?", value)
-	case "startsWith":
-		return query.Where(field+" ILIKE ?", value+"%")
-	case "endsWith":
-		return query.Where(field+" ILIKE ?", "%"+value)
-	default:
-		return query
+	if err := json.NewDecoder(r.Body).Decode(&roleData); err != nil {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if id, found, err := requestedUserID(r); err != nil {
+		utils.SendErrorResponse(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	} else if found {
+		roleData.UserID = id
+	}
+	if roleData.UserID == 0 {
+		utils.SendErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidRole(roleData.Role) {
+		utils.SendErrorResponse(w, "Unknown role", http.StatusBadRequest)
+		return
 	}
-}
 
-func applyDateFilter(query *gorm.DB, field, value, operator string) *gorm.DB {
-	switch operator {
-	case "equals":
-		return query.Where(field+" BETWEEN ? AND ?", value, value+"T23:59:59Z")
-	case "before":
-		return query.Where(field+" < ?", value)
-	case "after":
-		return query.Where(field+" > ?", value)
-	default:
-		return query
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	// Admins demoting themselves could leave nobody able to manage roles
+	if caller.UserID == roleData.UserID {
+		utils.SendErrorResponse(w, "You cannot change your own role", http.StatusForbidden)
+		return
+	}
+
+	user, err := h.Users.FindByID(r.Context(), roleData.UserID)
+	if err != nil {
+		utils.SendErrorResponse(w, "User not found", http.StatusNotFound)
+		return
 	}
+
+	user.Role = roleData.Role
+	user.UpdatedAt = time.Now().UTC()
+
+	if err := h.Users.UpdateRole(r.Context(), user); err != nil {
+		utils.SendErrorResponse(w, "Could not update role", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "User role updated successfully",
+		Data:    user,
+	})
 }
 
-func applySorting(query *gorm.DB, field, direction string) *gorm.DB {
-	if field == "" {
-		return query.Order("user_id asc")
+// requestedUserID returns the {id} route variable, or the id query parameter
+// used by the deprecated /user/* routes. found is false when neither is set.
+func requestedUserID(r *http.Request) (id uint, found bool, err error) {
+	raw, ok := mux.Vars(r)["id"]
+	if !ok {
+		raw = r.URL.Query().Get("id")
+	}
+	if raw == "" {
+		return 0, false, nil
 	}
 
-	if direction != "desc" {
-		direction = "asc"
+	n, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || n == 0 {
+		return 0, true, utils.ErrInvalidInput
 	}
+	return uint(n), true, nil
+}
+
+// usesField reports whether opts filters or sorts by the field called name.
+func usesField(opts repository.UserListOptions, name string) bool {
+	for _, condition := range listquery.Conditions(opts.Filter) {
+		if condition.Field.Name == name {
+			return true
+		}
+	}
+	for _, sort := range opts.Sort {
+		if sort.Field.Name == name {
+			return true
+		}
+	}
+	return false
+}
 
-	return query.Order(fmt.Sprintf("%s %s", field, direction))
+// canManageUser reports whether caller may modify the account targetID,
+// which is always true for their own account and otherwise needs perm.
+func canManageUser(caller *models.User, targetID uint, perm middleware.Permission) bool {
+	return caller.UserID == targetID || middleware.Can(caller, perm)
 }
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"main/internal/models"
+)
+
+func TestUserCreate(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.addUser(t, "Admin", models.RoleAdmin)
+
+	w := serve(env.userAPI.Create, http.MethodPost, "/api/v1/users",
+		`{"user_name":"  Dana ","user_email":" Dana@Example.COM "}`, admin, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var created models.User
+	decode(t, w, &created)
+	if created.UserName != "Dana" || created.UserEmail != "dana@example.com" {
+		t.Errorf("stored %q <%s>, want trimmed name and normalized email", created.UserName, created.UserEmail)
+	}
+	if created.EmailVerifiedAt != nil {
+		t.Error("new accounts must start unverified")
+	}
+	waitForMail(t)
+	if got := env.outgoing.recipients(); !slices.Equal(got, []string{"dana@example.com"}) {
+		t.Errorf("verification mail sent to %v", got)
+	}
+
+	w = serve(env.userAPI.Create, http.MethodPost, "/api/v1/users",
+		`{"user_name":"Other","user_email":"DANA@example.com"}`, admin, nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate email: status = %d, want 409", w.Code)
+	}
+
+	w = serve(env.userAPI.Create, http.MethodPost, "/api/v1/users",
+		`{"user_name":"  ","user_email":"x@example.com"}`, admin, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("blank name: status = %d, want 400", w.Code)
+	}
+}
+
+func TestUserCreateIgnoresPrivilegedFields(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.addUser(t, "Admin", models.RoleAdmin)
+
+	w := serve(env.userAPI.Create, http.MethodPost, "/api/v1/users",
+		`{"user_id":99,"user_name":"Eve","user_email":"eve@example.com","role":"admin",
+		  "totp_enabled":true,"email_verified_at":"2024-01-01T00:00:00Z"}`, admin, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var created models.User
+	decode(t, w, &created)
+	waitForMail(t)
+
+	stored, err := env.users.FindByID(context.Background(), created.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.UserID == 99 {
+		t.Error("user ID taken from the request")
+	}
+	if stored.Role != models.RoleUser {
+		t.Errorf("role = %q, want %q", stored.Role, models.RoleUser)
+	}
+	if stored.TOTPEnabled {
+		t.Error("totp_enabled taken from the request")
+	}
+	if stored.EmailVerifiedAt != nil {
+		t.Error("email_verified_at taken from the request")
+	}
+}
+
+func TestUserUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+	carol := env.addUser(t, "Carol", models.RoleUser)
+	admin := env.addUser(t, "Admin", models.RoleAdmin)
+
+	tests := []struct {
+		desc       string
+		caller     *models.User
+		target     *models.User
+		body       string
+		wantStatus int
+	}{
+		{"own account", bob, bob, `{"user_name":"Bobby"}`, http.StatusOK},
+		{"someone else's account", bob, carol, `{"user_name":"Hacked"}`, http.StatusForbidden},
+		{"admin edits another account", admin, carol, `{"user_name":"Caroline"}`, http.StatusOK},
+		{"taken email", bob, bob, `{"user_email":"CAROL@example.com"}`, http.StatusConflict},
+		{"invalid email", bob, bob, `{"user_email":"not-an-address"}`, http.StatusBadRequest},
+		{"blank name", bob, bob, `{"user_name":"   "}`, http.StatusBadRequest},
+		{"malformed body", bob, bob, `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			vars := map[string]string{"id": strconv.Itoa(int(tt.target.UserID))}
+			w := serve(env.userAPI.Update, http.MethodPut, "/api/v1/users/"+vars["id"], tt.body, tt.caller, vars)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	stored, err := env.users.FindByID(context.Background(), carol.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.UserName != "Caroline" {
+		t.Errorf("carol's name = %q, want Caroline", stored.UserName)
+	}
+}
+
+func TestUserUpdateEmailRequiresVerification(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+
+	vars := map[string]string{"id": strconv.Itoa(int(bob.UserID))}
+	w := serve(env.userAPI.Update, http.MethodPut, "/api/v1/users/"+vars["id"], `{"user_email":"Bob2@Example.com"}`, bob, vars)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	stored, err := env.users.FindByID(context.Background(), bob.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.UserEmail != "bob2@example.com" {
+		t.Errorf("email = %q, want it normalized for login", stored.UserEmail)
+	}
+	if stored.EmailVerifiedAt != nil {
+		t.Error("a changed email must be verified again")
+	}
+	if stored.PasswordHash != bob.PasswordHash {
+		t.Error("updating the profile must not touch the password")
+	}
+	waitForMail(t)
+	if got := env.outgoing.recipients(); !slices.Equal(got, []string{"bob2@example.com"}) {
+		t.Errorf("verification mail sent to %v", got)
+	}
+}
+
+func TestUserList(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.addUser(t, "Anna", models.RoleUser)
+	env.addUser(t, "Annie", models.RoleModerator)
+	env.addUser(t, "Bob", models.RoleUser)
+	admin := env.addUser(t, "Admin", models.RoleAdmin)
+
+	tests := []struct {
+		desc       string
+		caller     *models.User
+		query      string
+		wantStatus int
+		wantNames  []string
+	}{
+		{"everyone", user, "", http.StatusOK, []string{"Anna", "Annie", "Bob", "Admin"}},
+		{"filter and sort", user, "filter[name][startsWith]=ann&sort_field=name&sort_dir=desc", http.StatusOK, []string{"Annie", "Anna"}},
+		{"or group", user, "filter[or][0][role][equals]=admin&filter[or][1][name][equals]=Bob", http.StatusOK, []string{"Bob", "Admin"}},
+		{"legacy triple", user, "filter_field=name&filter_operator=contains&filter_value=ann", http.StatusOK, []string{"Anna", "Annie"}},
+		{"legacy sort column", user, "sort_field=user_name", http.StatusOK, []string{"Admin", "Anna", "Annie", "Bob"}},
+		{"unknown sort field", user, "sort_field=user_id;drop", http.StatusBadRequest, nil},
+		{"unknown operator", user, "filter[name][like]=a", http.StatusBadRequest, nil},
+		{"private field", user, "filter[email][contains]=example", http.StatusForbidden, nil},
+		{"private field with permission", admin, "filter[email][startsWith]=bob", http.StatusOK, []string{"Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			w := serve(env.userAPI.List, http.MethodGet, "/api/v1/users?"+strings.ReplaceAll(tt.query, ";", "%3B"), "", tt.caller, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantNames == nil {
+				return
+			}
+			var users []models.User
+			decode(t, w, &users)
+			var names []string
+			for _, u := range users {
+				names = append(names, u.UserName)
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("got %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestUserListHidesPrivateFields(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.addUser(t, "Anna", models.RoleUser)
+
+	w := serve(env.userAPI.List, http.MethodGet, "/api/v1/users", "", user, nil)
+	if strings.Contains(w.Body.String(), "@example.com") {
+		t.Errorf("list exposes emails to regular users: %s", w.Body)
+	}
+}
+
+func TestUserGetAndDelete(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+	carol := env.addUser(t, "Carol", models.RoleUser)
+	admin := env.addUser(t, "Admin", models.RoleAdmin)
+	vars := func(u *models.User) map[string]string { return map[string]string{"id": strconv.Itoa(int(u.UserID))} }
+
+	w := serve(env.userAPI.Get, http.MethodGet, "/api/v1/users/x", "", bob, vars(carol))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "carol@example.com") {
+		t.Errorf("get other user: status %d, body %s", w.Code, w.Body)
+	}
+	w = serve(env.userAPI.Get, http.MethodGet, "/api/v1/users/x", "", bob, map[string]string{"id": "999"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get missing user: status = %d, want 404", w.Code)
+	}
+
+	w = serve(env.userAPI.Delete, http.MethodDelete, "/api/v1/users/x", "", bob, vars(carol))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("delete other user: status = %d, want 403", w.Code)
+	}
+	w = serve(env.userAPI.Delete, http.MethodDelete, "/api/v1/users/x", "", admin, vars(carol))
+	if w.Code != http.StatusOK {
+		t.Errorf("admin delete: status = %d, body %s", w.Code, w.Body)
+	}
+	if _, err := env.users.FindByID(context.Background(), carol.UserID); err == nil {
+		t.Error("carol still exists after delete")
+	}
+}
+
+func TestUserUpdateRole(t *testing.T) {
+	env := newTestEnv(t)
+	bob := env.addUser(t, "Bob", models.RoleUser)
+	admin := env.addUser(t, "Admin", models.RoleAdmin)
+	vars := func(u *models.User) map[string]string { return map[string]string{"id": strconv.Itoa(int(u.UserID))} }
+
+	w := serve(env.userAPI.UpdateRole, http.MethodPut, "/", `{"role":"superuser"}`, admin, vars(bob))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown role: status = %d, want 400", w.Code)
+	}
+	w = serve(env.userAPI.UpdateRole, http.MethodPut, "/", `{"role":"user"}`, admin, vars(admin))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("own role: status = %d, want 403", w.Code)
+	}
+	w = serve(env.userAPI.UpdateRole, http.MethodPut, "/", `{"role":"moderator"}`, admin, vars(bob))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	stored, _ := env.users.FindByID(context.Background(), bob.UserID)
+	if stored.Role != models.RoleModerator {
+		t.Errorf("role = %q, want moderator", stored.Role)
+	}
+}
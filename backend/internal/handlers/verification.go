@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/internal/middleware"
+	"main/internal/models"
+	"main/internal/utils"
+	"main/pkg/mailer"
+)
+
+const (
+	verifyEmailPurpose  = "verify-email"
+	verificationLinkTTL = 24 * time.Hour
+)
+
+// VerifyEmail marks the account named by a verification link as verified.
+func (h *AuthHandlers) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		utils.SendErrorResponse(w, "Missing 'token' parameter", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := utils.VerifySignedToken(verifyEmailPurpose, token)
+	if err != nil {
+		if errors.Is(err, utils.ErrExpiredSignedToken) {
+			utils.SendErrorResponse(w, "Verification link has expired", http.StatusGone)
+			return
+		}
+		utils.SendErrorResponse(w, "Invalid verification link", http.StatusBadRequest)
+		return
+	}
+
+	// The payload binds the link to the address it was sent to, so changing
+	// email invalidates any earlier link.
+	idStr, email, found := strings.Cut(payload, ":")
+	userID, convErr := strconv.ParseUint(idStr, 10, 32)
+	if !found || convErr != nil {
+		utils.SendErrorResponse(w, "Invalid verification link", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Users.FindByID(r.Context(), uint(userID))
+	if err != nil || user.UserEmail != email {
+		utils.SendErrorResponse(w, "Invalid verification link", http.StatusBadRequest)
+		return
+	}
+
+	if !user.IsEmailVerified() {
+		now := time.Now().UTC()
+		user.EmailVerifiedAt = &now
+		user.UpdatedAt = now
+		if err := h.Users.UpdateEmailVerified(r.Context(), user); err != nil {
+			utils.SendErrorResponse(w, "Could not verify email", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Email verified successfully",
+		Data:    user,
+	})
+}
+
+// ResendVerification mails the caller a fresh verification link.
+func (h *AuthHandlers) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		utils.SendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if caller.IsEmailVerified() {
+		utils.SendErrorResponse(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	if err := sendVerificationEmail(r.Context(), *caller); err != nil {
+		utils.SendErrorResponse(w, "Could not send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, models.ResponseData{
+		Status:  "success",
+		Message: "Verification email sent",
+	})
+}
+
+func sendVerificationEmail(ctx context.Context, user models.User) error {
+	payload := fmt.Sprintf("%d:%s", user.UserID, user.UserEmail)
+	token := utils.SignToken(verifyEmailPurpose, payload, time.Now().Add(verificationLinkTTL))
+	link := settings.PublicBaseURL + "/api/v1/auth/verify?token=" + url.QueryEscape(token)
+
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours. If you did not create an account, you can ignore this message.\n",
+		user.UserName, link, int(verificationLinkTTL.Hours()))
+
+	return mailer.SendText(ctx, user.UserEmail, "Verify your email address", body)
+}
+
+// sendVerificationEmailAsync is used after account changes that should not
+// fail because the mail relay is unavailable; the user can request a resend.
+// Failures are logged with the logger of the request in ctx.
+func sendVerificationEmailAsync(ctx context.Context, user models.User) {
+	ctx = context.WithoutCancel(ctx)
+	logger := utils.LoggerFromContext(ctx)
+	runInBackground(func() {
+		if err := sendVerificationEmail(ctx, user); err != nil {
+			logger.WithError(err).WithField("user_id", user.UserID).Error("Failed to send verification email")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package listquery
+
+import "gorm.io/gorm/clause"
+
+// Expr is a node of a filter tree: a Condition or a Group.
+type Expr interface {
+	// Matches evaluates the filter against a record held in memory; value
+	// returns the record's value in a column.
+	Matches(value func(column string) any) bool
+	expression(dialect string) clause.Expression
+}
+
+// Group combines filters: a record matches when it meets all of them, or
+// any of them when Or is set. An empty group matches everything.
+type Group struct {
+	Or    bool
+	Exprs []Expr
+}
+
+// And combines exprs so that all must match, skipping nil ones. It returns
+// nil when no filter is left and the expression itself when one is.
+func And(exprs ...Expr) Expr {
+	return combine(false, exprs)
+}
+
+// Or combines exprs so that any may match, skipping nil ones like And.
+func Or(exprs ...Expr) Expr {
+	return combine(true, exprs)
+}
+
+func combine(or bool, exprs []Expr) Expr {
+	var kept []Expr
+	for _, expr := range exprs {
+		if expr != nil {
+			kept = append(kept, expr)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return nil
+	case 1:
+		return kept[0]
+	}
+	return Group{Or: or, Exprs: kept}
+}
+
+// Conditions returns every condition in expr, depth first.
+func Conditions(expr Expr) []Condition {
+	switch expr := expr.(type) {
+	case Condition:
+		return []Condition{expr}
+	case Group:
+		var conditions []Condition
+		for _, child := range expr.Exprs {
+			conditions = append(conditions, Conditions(child)...)
+		}
+		return conditions
+	}
+	return nil
+}
+
+// Matches reports whether the record meets all or any of the group's filters.
+func (g Group) Matches(value func(column string) any) bool {
+	if len(g.Exprs) == 0 {
+		return true
+	}
+	for _, expr := range g.Exprs {
+		if expr.Matches(value) == g.Or {
+			return g.Or
+		}
+	}
+	return !g.Or
+}
@@ -0,0 +1,114 @@
+package listquery
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Where returns a scope applying filter; a nil filter selects everything.
+func Where(filter Expr) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter == nil {
+			return db
+		}
+		return db.Where(filter.expression(db.Dialector.Name()))
+	}
+}
+
+// OrderBy returns a scope sorting by each field in turn.
+func OrderBy(sorts ...Sort) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, s := range sorts {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: s.Field.Column}, Desc: s.Desc})
+		}
+		return db
+	}
+}
+
+func (g Group) expression(dialect string) clause.Expression {
+	exprs := make([]clause.Expression, len(g.Exprs))
+	for i, expr := range g.Exprs {
+		exprs[i] = expr.expression(dialect)
+	}
+	if len(exprs) == 0 {
+		return clause.Expr{SQL: "1 = 1"}
+	}
+	if g.Or {
+		return clause.Or(exprs...)
+	}
+	return clause.And(exprs...)
+}
+
+// expression builds the condition as SQL. Case-insensitive matches compare
+// lowercased values, which behaves the same on Postgres and SQLite, unlike
+// ILIKE. SQLite's LIKE ignores ASCII case, so case-sensitive pattern
+// matches use GLOB there instead.
+func (c Condition) expression(dialect string) clause.Expression {
+	var column any = clause.Column{Name: c.Field.Column}
+	if c.Operator == IsNull {
+		if c.Value.(bool) {
+			return clause.Eq{Column: column, Value: nil}
+		}
+		return clause.Neq{Column: column, Value: nil}
+	}
+	if c.Field.Type == String && !c.CaseSensitive {
+		column = clause.Expr{SQL: "LOWER(?)", Vars: []any{column}}
+	}
+	operand := c.foldCase(c.Value)
+
+	switch c.Operator {
+	case Equals, Between:
+		if c.Upper == nil {
+			return compare(column, "=", operand)
+		}
+		upper := "<="
+		if c.ExcludeUpper {
+			upper = "<"
+		}
+		return clause.And(compare(column, ">=", operand), compare(column, upper, c.foldCase(c.Upper)))
+	case In:
+		values := make([]any, len(c.Values))
+		for i, value := range c.Values {
+			values[i] = c.foldCase(value)
+		}
+		return clause.Expr{SQL: "? IN ?", Vars: []any{column, values}}
+	case Contains, StartsWith, EndsWith:
+		s, _ := operand.(string)
+		if c.CaseSensitive && dialect == "sqlite" {
+			return clause.Expr{SQL: "? GLOB ?", Vars: []any{column, pattern(c.Operator, escapeGlob(s), "*")}}
+		}
+		return clause.Expr{SQL: `? LIKE ? ESCAPE '\'`, Vars: []any{column, pattern(c.Operator, escapeLike(s), "%")}}
+	case Before:
+		return compare(column, "<", operand)
+	case After:
+		return compare(column, ">", operand)
+	}
+	panic("listquery: unhandled operator " + string(c.Operator))
+}
+
+func compare(column any, op string, value any) clause.Expression {
+	return clause.Expr{SQL: "? " + op + " ?", Vars: []any{column, value}}
+}
+
+// pattern surrounds an escaped value with wildcards as op requires.
+func pattern(op Operator, escaped, wildcard string) string {
+	switch op {
+	case StartsWith:
+		return escaped + wildcard
+	case EndsWith:
+		return wildcard + escaped
+	}
+	return wildcard + escaped + wildcard
+}
+
+// escapeLike makes LIKE treat its wildcards in s literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// escapeGlob makes GLOB treat its wildcards in s literally.
+func escapeGlob(s string) string {
+	return strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]").Replace(s)
+}
@@ -0,0 +1,133 @@
+package listquery
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type record struct {
+	ID         int64 `gorm:"primaryKey"`
+	Name       string
+	CreatedAt  time.Time
+	ArchivedAt *time.Time
+}
+
+func (r record) column(name string) any {
+	switch name {
+	case "id":
+		return r.ID
+	case "name":
+		return r.Name
+	case "created_at":
+		return r.CreatedAt
+	case "archived_at":
+		if r.ArchivedAt == nil {
+			return nil
+		}
+		return *r.ArchivedAt
+	}
+	panic("unknown column " + name)
+}
+
+// TestSQLMatchesMemory checks that the GORM scope selects exactly the
+// records Matches accepts.
+func TestSQLMatchesMemory(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&record{}); err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(day, hour int) time.Time { return time.Date(2026, 1, day, hour, 0, 0, 0, time.UTC) }
+	archived := at(20, 0)
+	records := []record{
+		{ID: 1, Name: "Anna", CreatedAt: at(1, 9), ArchivedAt: &archived},
+		{ID: 2, Name: "bob_smith", CreatedAt: at(2, 12)},
+		{ID: 3, Name: "annie", CreatedAt: at(5, 23)},
+		{ID: 4, Name: "Carl*?[x]", CreatedAt: at(9, 0), ArchivedAt: &archived},
+		{ID: 5, Name: "100% Dan", CreatedAt: at(9, 6)},
+	}
+	if err := db.Create(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	queries := []string{
+		"filter[name][contains]=ann",
+		"filter[name][contains:cs]=ann",
+		"filter[name][startsWith]=A",
+		"filter[name][startsWith:cs]=A",
+		"filter[name][endsWith]=SMITH",
+		"filter[name][equals]=anna",
+		"filter[name][equals:ci]=anna",
+		"filter[name][in]=Anna,annie",
+		"filter[name][in:ci]=ANNA,ANNIE",
+		"filter[name][contains]=_",
+		"filter[name][contains]=%25",
+		"filter[name][contains:cs]=*?",
+		"filter[name][contains:cs]=[x]",
+		"filter[name][endsWith:cs]=*",
+		"filter[id][in]=1,3,5",
+		"filter[id][between]=2,4",
+		"filter[created_at][equals]=2026-01-09",
+		"filter[created_at][between]=2026-01-02,2026-01-05",
+		"filter[created_at][between]=2026-01-02T12:00:00Z,2026-01-05T23:00:00Z",
+		"filter[created_at][after]=2026-01-02T15:00:00%2B05:00",
+		"filter[created_at][before]=2026-01-02T15:00:00%2B05:00",
+		"filter[archived_at][isnull]=true",
+		"filter[archived_at][isnull]=false",
+		"filter[archived_at][before]=2026-02-01",
+		"filter[name][contains]=a&filter[created_at][after]=2026-01-02",
+		"filter[or][0][name][startsWith]=b&filter[or][1][id][equals]=4",
+		"filter[or][0][and][0][archived_at][isnull]=true&filter[or][0][and][1][id][between]=3,5&filter[or][1][name][equals]=Anna",
+		"filter[id][in]=1,2,3&filter[or][0][name][contains]=bob&filter[or][1][archived_at][isnull]=false",
+	}
+	for _, raw := range queries {
+		t.Run(raw, func(t *testing.T) {
+			query, err := url.ParseQuery(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := testFields.ParseFilter(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var fromSQL []int64
+			if err := db.Model(&record{}).Scopes(Where(filter)).Order("id").Pluck("id", &fromSQL).Error; err != nil {
+				t.Fatal(err)
+			}
+			var fromMemory []int64
+			for _, r := range records {
+				if filter.Matches(r.column) {
+					fromMemory = append(fromMemory, r.ID)
+				}
+			}
+			if !slices.Equal(fromSQL, fromMemory) {
+				t.Errorf("SQL selected %v, memory matched %v", fromSQL, fromMemory)
+			}
+		})
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort, err := testFields.Sort("created_at", "desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := OrderBy(sort)(db.Model(&record{})).Order("id").Find(&[]record{}).Statement
+	if got, want := stmt.SQL.String(), "SELECT * FROM `records` ORDER BY `created_at` DESC,id"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
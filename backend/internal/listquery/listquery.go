@@ -0,0 +1,299 @@
+// Package listquery validates the filtering and sorting parameters of list
+// endpoints against a declarative registry of the fields each endpoint
+// exposes, and translates them into GORM scopes or in-memory comparisons.
+//
+// Filters form a tree of Conditions combined by Groups; ParseFilter reads
+// one from filter[...] query parameters.
+//
+// Only registered fields ever reach SQL, and always by their registered
+// column name, so request parameters cannot inject into queries.
+package listquery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is wrapped by every error caused by bad parameters, which
+// handlers answer with 400 Bad Request.
+var ErrInvalidQuery = errors.New("invalid query")
+
+var (
+	ErrUnknownField    = fmt.Errorf("%w: unknown field", ErrInvalidQuery)
+	ErrUnknownOperator = fmt.Errorf("%w: unsupported operator", ErrInvalidQuery)
+	ErrInvalidValue    = fmt.Errorf("%w: invalid value", ErrInvalidQuery)
+)
+
+// Type determines how filter values are parsed and compared.
+type Type int
+
+const (
+	String Type = iota
+	Number
+	Time
+)
+
+// Operator compares a field with a filter value.
+type Operator string
+
+const (
+	Equals     Operator = "equals"
+	Contains   Operator = "contains"
+	StartsWith Operator = "startsWith"
+	EndsWith   Operator = "endsWith"
+	Before     Operator = "before"
+	After      Operator = "after"
+	// In matches any of a comma-separated list of values.
+	In Operator = "in"
+	// Between matches an inclusive range given as "low,high".
+	Between Operator = "between"
+	// IsNull takes true or false and matches missing or present values.
+	IsNull Operator = "isnull"
+)
+
+// caseInsensitiveByDefault lists the string operators that ignore case
+// unless asked otherwise; the rest match exactly.
+var caseInsensitiveByDefault = map[Operator]bool{Contains: true, StartsWith: true, EndsWith: true}
+
+// caseOptional lists the operators whose case sensitivity can be chosen.
+var caseOptional = map[Operator]bool{Equals: true, In: true, Contains: true, StartsWith: true, EndsWith: true}
+
+// Case sensitivity suffixes, as in filter[name][equals:ci]=ann.
+const (
+	caseSensitiveSuffix   = ":cs"
+	caseInsensitiveSuffix = ":ci"
+)
+
+// MaxValues caps the values of a single In condition.
+const MaxValues = 100
+
+// Field is one filterable or sortable attribute of a listed record.
+type Field struct {
+	// Name is the public name used in query parameters.
+	Name string
+	// Aliases are older public names that keep being accepted.
+	Aliases []string
+	Column  string
+	Type    Type
+	// Operators lists the filters the field supports; none makes it
+	// unfilterable.
+	Operators []Operator
+	Sortable  bool
+}
+
+func (f Field) supports(op Operator) bool {
+	for _, supported := range f.Operators {
+		if supported == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry holds the fields one list endpoint exposes.
+type Registry struct {
+	fields []Field
+	byName map[string]Field
+}
+
+// NewRegistry builds a registry from fields. It panics when two fields share
+// a name or alias, since registries are declared at package level.
+func NewRegistry(fields ...Field) *Registry {
+	r := &Registry{fields: fields, byName: make(map[string]Field)}
+	for _, field := range fields {
+		for _, name := range append([]string{field.Name}, field.Aliases...) {
+			if name == groupAnd || name == groupOr {
+				panic(fmt.Sprintf("listquery: field name %q is reserved for groups", name))
+			}
+			if _, taken := r.byName[name]; taken {
+				panic(fmt.Sprintf("listquery: field name %q registered twice", name))
+			}
+			r.byName[name] = field
+		}
+	}
+	return r
+}
+
+// Lookup returns the field registered under name or one of its aliases.
+func (r *Registry) Lookup(name string) (Field, bool) {
+	field, ok := r.byName[name]
+	return field, ok
+}
+
+// Condition is a validated filter on a single field. Values hold a string,
+// int64 or time.Time depending on the field's type.
+type Condition struct {
+	Field    Field
+	Operator Operator
+	// Value is the operand, the lower bound of Between, or a bool for IsNull.
+	Value any
+	// Values are the operands of In.
+	Values []any
+	// Upper bounds Between, and turns Equals into a range, e.g. a whole day
+	// for a date without a time.
+	Upper any
+	// ExcludeUpper makes the range half-open: [Value, Upper).
+	ExcludeUpper bool
+	// CaseSensitive applies to string fields only.
+	CaseSensitive bool
+}
+
+// Sort orders results by a sortable field.
+type Sort struct {
+	Field Field
+	Desc  bool
+}
+
+// Filter validates a filter on the field called name and parses raw
+// according to the field's type. operator may end in :cs or :ci to make a
+// string match case-sensitive or not.
+func (r *Registry) Filter(name, operator, raw string) (Condition, error) {
+	field, ok := r.Lookup(name)
+	if !ok || len(field.Operators) == 0 {
+		return Condition{}, fmt.Errorf("%w %q, expected one of %s", ErrUnknownField, name, r.names(true))
+	}
+
+	op, caseSensitive, caseSet := splitCase(operator)
+	if !field.supports(op) {
+		return Condition{}, fmt.Errorf("%w %q for %s, expected one of %s", ErrUnknownOperator, op, field.Name, joinOperators(field.Operators))
+	}
+	if caseSet && (field.Type != String || !caseOptional[op]) {
+		return Condition{}, fmt.Errorf("%w %q for %s: case sensitivity only applies to text matches", ErrUnknownOperator, operator, field.Name)
+	}
+	if !caseSet {
+		caseSensitive = !caseInsensitiveByDefault[op]
+	}
+
+	condition := Condition{Field: field, Operator: op, CaseSensitive: caseSensitive}
+	switch op {
+	case IsNull:
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%w for %s: isnull takes true or false, got %q", ErrInvalidValue, field.Name, raw)
+		}
+		condition.Value = isNull
+	case In:
+		parts := strings.Split(raw, ",")
+		if len(parts) > MaxValues {
+			return Condition{}, fmt.Errorf("%w for %s: in takes at most %d values", ErrInvalidValue, field.Name, MaxValues)
+		}
+		for _, part := range parts {
+			value, _, err := field.parse(part)
+			if err != nil {
+				return Condition{}, err
+			}
+			condition.Values = append(condition.Values, value)
+		}
+	case Between:
+		low, high, ok := strings.Cut(raw, ",")
+		if !ok {
+			return Condition{}, fmt.Errorf("%w for %s: between takes two values as low,high", ErrInvalidValue, field.Name)
+		}
+		lowValue, _, err := field.parse(low)
+		if err != nil {
+			return Condition{}, err
+		}
+		highValue, highDate, err := field.parse(high)
+		if err != nil {
+			return Condition{}, err
+		}
+		condition.Value, condition.Upper = lowValue, highValue
+		// A plain date as the upper bound includes that whole day
+		if highDate {
+			condition.Upper, condition.ExcludeUpper = highValue.(time.Time).AddDate(0, 0, 1), true
+		}
+	default:
+		value, dateOnly, err := field.parse(raw)
+		if err != nil {
+			return Condition{}, err
+		}
+		condition.Value = value
+		if dateOnly && op == Equals {
+			condition.Upper, condition.ExcludeUpper = value.(time.Time).AddDate(0, 0, 1), true
+		}
+	}
+	return condition, nil
+}
+
+// parse converts raw to the field's type. dateOnly reports a Time given as
+// a plain date.
+func (f Field) parse(raw string) (value any, dateOnly bool, err error) {
+	switch f.Type {
+	case Number:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w for %s: %q is not a whole number", ErrInvalidValue, f.Name, raw)
+		}
+		return n, false, nil
+	case Time:
+		t, dateOnly, err := parseTime(raw)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w for %s: %q is not a date (2006-01-02) or RFC 3339 time", ErrInvalidValue, f.Name, raw)
+		}
+		return t, dateOnly, nil
+	}
+	return raw, false, nil
+}
+
+// splitCase removes a case sensitivity suffix from operator.
+func splitCase(operator string) (op Operator, caseSensitive, ok bool) {
+	if base, found := strings.CutSuffix(operator, caseSensitiveSuffix); found {
+		return Operator(base), true, true
+	}
+	if base, found := strings.CutSuffix(operator, caseInsensitiveSuffix); found {
+		return Operator(base), false, true
+	}
+	return Operator(operator), false, false
+}
+
+// Sort validates sorting by the field called name in direction, which is
+// asc, desc or empty for ascending.
+func (r *Registry) Sort(name, direction string) (Sort, error) {
+	field, ok := r.Lookup(name)
+	if !ok || !field.Sortable {
+		return Sort{}, fmt.Errorf("%w %q for sorting, expected one of %s", ErrUnknownField, name, r.names(false))
+	}
+	switch direction {
+	case "", "asc":
+		return Sort{Field: field}, nil
+	case "desc":
+		return Sort{Field: field, Desc: true}, nil
+	default:
+		return Sort{}, fmt.Errorf("%w: sort direction must be asc or desc, got %q", ErrInvalidQuery, direction)
+	}
+}
+
+// names lists the public names of filterable or sortable fields.
+func (r *Registry) names(filterable bool) string {
+	var names []string
+	for _, field := range r.fields {
+		if (filterable && len(field.Operators) > 0) || (!filterable && field.Sortable) {
+			names = append(names, field.Name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func joinOperators(ops []Operator) string {
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = string(op)
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseTime accepts RFC 3339 times and plain dates, which are taken as
+// midnight UTC. Times are converted to UTC because SQLite compares them as
+// text; this relies on the repositories writing every timestamp in UTC.
+func parseTime(raw string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), false, nil
+	}
+	t, err = time.Parse("2006-01-02", raw)
+	return t, true, err
+}
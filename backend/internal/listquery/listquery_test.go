@@ -0,0 +1,224 @@
+package listquery
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFields = NewRegistry(
+	Field{Name: "id", Column: "id", Type: Number, Operators: []Operator{Equals, In, Between}, Sortable: true},
+	Field{Name: "name", Aliases: []string{"user_name"}, Column: "name", Type: String,
+		Operators: []Operator{Equals, In, Contains, StartsWith, EndsWith}, Sortable: true},
+	Field{Name: "created_at", Aliases: []string{"date"}, Column: "created_at", Type: Time,
+		Operators: []Operator{Equals, Before, After, Between}, Sortable: true},
+	Field{Name: "archived_at", Column: "archived_at", Type: Time, Operators: []Operator{Before, IsNull}},
+	Field{Name: "secret", Column: "secret", Type: String},
+)
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name, direction string
+		wantColumn      string
+		wantDesc        bool
+		wantErr         error
+	}{
+		{name: "name", wantColumn: "name"},
+		{name: "name", direction: "asc", wantColumn: "name"},
+		{name: "created_at", direction: "desc", wantColumn: "created_at", wantDesc: true},
+		{name: "user_name", direction: "desc", wantColumn: "name", wantDesc: true},
+		{name: "name", direction: "up", wantErr: ErrInvalidQuery},
+		{name: "name; DROP TABLE users", wantErr: ErrUnknownField},
+		{name: "password_hash", wantErr: ErrUnknownField},
+		{name: "archived_at", wantErr: ErrUnknownField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.direction, func(t *testing.T) {
+			sort, err := testFields.Sort(tt.name, tt.direction)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sort.Field.Column != tt.wantColumn || sort.Desc != tt.wantDesc {
+				t.Errorf("got %s desc=%v, want %s desc=%v", sort.Field.Column, sort.Desc, tt.wantColumn, tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		desc                  string
+		name, operator, value string
+		want                  Condition
+		wantErr               error
+	}{
+		{desc: "contains ignores case by default", name: "name", operator: "contains", value: "Ann",
+			want: Condition{Operator: Contains, Value: "Ann"}},
+		{desc: "equals matches case by default", name: "name", operator: "equals", value: "Ann",
+			want: Condition{Operator: Equals, Value: "Ann", CaseSensitive: true}},
+		{desc: "cs suffix", name: "name", operator: "contains:cs", value: "Ann",
+			want: Condition{Operator: Contains, Value: "Ann", CaseSensitive: true}},
+		{desc: "ci suffix", name: "name", operator: "equals:ci", value: "Ann",
+			want: Condition{Operator: Equals, Value: "Ann"}},
+		{desc: "in", name: "id", operator: "in", value: "1,2,3",
+			want: Condition{Operator: In, Values: []any{int64(1), int64(2), int64(3)}, CaseSensitive: true}},
+		{desc: "between numbers", name: "id", operator: "between", value: "2,5",
+			want: Condition{Operator: Between, Value: int64(2), Upper: int64(5), CaseSensitive: true}},
+		{desc: "between dates covers the last day", name: "created_at", operator: "between", value: "2026-01-02,2026-01-05",
+			want: Condition{Operator: Between, Value: day(2), Upper: day(6), ExcludeUpper: true, CaseSensitive: true}},
+		{desc: "equals date covers the day", name: "date", operator: "equals", value: "2026-01-02",
+			want: Condition{Operator: Equals, Value: day(2), Upper: day(3), ExcludeUpper: true, CaseSensitive: true}},
+		{desc: "times are converted to UTC", name: "created_at", operator: "after", value: "2026-01-02T05:00:00+05:00",
+			want: Condition{Operator: After, Value: day(2), CaseSensitive: true}},
+		{desc: "isnull", name: "archived_at", operator: "isnull", value: "true",
+			want: Condition{Operator: IsNull, Value: true, CaseSensitive: true}},
+
+		{desc: "unknown field", name: "password", operator: "equals", value: "x", wantErr: ErrUnknownField},
+		{desc: "field without operators", name: "secret", operator: "equals", value: "x", wantErr: ErrUnknownField},
+		{desc: "unknown operator", name: "name", operator: "like", value: "x", wantErr: ErrUnknownOperator},
+		{desc: "operator not allowed for field", name: "id", operator: "contains", value: "1", wantErr: ErrUnknownOperator},
+		{desc: "case suffix on a time", name: "created_at", operator: "before:ci", value: "2026-01-01", wantErr: ErrUnknownOperator},
+		{desc: "case suffix on between", name: "id", operator: "between:cs", value: "1,2", wantErr: ErrUnknownOperator},
+		{desc: "bad number", name: "id", operator: "equals", value: "1 OR 1=1", wantErr: ErrInvalidValue},
+		{desc: "bad time", name: "created_at", operator: "after", value: "yesterday", wantErr: ErrInvalidValue},
+		{desc: "bad in member", name: "id", operator: "in", value: "1,x", wantErr: ErrInvalidValue},
+		{desc: "between needs two values", name: "id", operator: "between", value: "1", wantErr: ErrInvalidValue},
+		{desc: "isnull needs a bool", name: "archived_at", operator: "isnull", value: "maybe", wantErr: ErrInvalidValue},
+		{desc: "too many in values", name: "id", operator: "in", value: strings.Repeat("1,", MaxValues) + "1", wantErr: ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := testFields.Filter(tt.name, tt.operator, tt.value)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrInvalidQuery) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.Field = Field{}
+			if !equalConditions(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalConditions(a, b Condition) bool {
+	if a.Operator != b.Operator || a.ExcludeUpper != b.ExcludeUpper || a.CaseSensitive != b.CaseSensitive ||
+		!equalValues(a.Value, b.Value) || !equalValues(a.Upper, b.Upper) || len(a.Values) != len(b.Values) {
+		return false
+	}
+	for i := range a.Values {
+		if !equalValues(a.Values[i], b.Values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalValues(a, b any) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb) && ta.Location() == tb.Location()
+	}
+	return a == b
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		query string
+		// want describes the tree: conditions by field and operator, groups
+		// as and(...) or or(...)
+		want    string
+		wantErr bool
+	}{
+		{query: "", want: "<nil>"},
+		{query: "page=2&filter_field=name", want: "<nil>"},
+		{query: "filter[name][contains]=ann", want: "name contains"},
+		{query: "filter[name][contains]=ann&filter[created_at][after]=2026-01-01",
+			want: "and(created_at after, name contains)"},
+		{query: "filter[name][contains]=a&filter[name][contains]=n", want: "and(name contains, name contains)"},
+		{query: "filter[or][0][name][equals]=a&filter[or][1][id][in]=1,2",
+			want: "or(name equals, id in)"},
+		{query: "filter[or][1][id][equals]=1&filter[or][0][and][0][name][equals]=a&filter[or][0][and][1][id][between]=1,2",
+			want: "or(and(name equals, id between), id equals)"},
+		{query: "filter[or][10][id][equals]=1&filter[or][9][id][equals]=2", want: "or(id equals, id equals)"},
+		{query: "filter[name]=ann", wantErr: true},
+		{query: "filter[name][equals][x]=ann", wantErr: true},
+		{query: "filter[name[equals]=ann", wantErr: true},
+		{query: "filter[][equals]=ann", wantErr: true},
+		{query: "filter[or]=1", wantErr: true},
+		{query: "filter[or][first][id][equals]=1", wantErr: true},
+		{query: "filter[or][0]=1", wantErr: true},
+		{query: "filter[or][0][or][0][or][0][or][0][id][equals]=1", wantErr: true},
+		{query: strings.Repeat("filter[id][equals]=1&", MaxConditions+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expr, err := testFields.ParseFilter(query)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidQuery) {
+					t.Fatalf("err = %v, want ErrInvalidQuery", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(expr); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func describe(expr Expr) string {
+	switch expr := expr.(type) {
+	case Condition:
+		return expr.Field.Name + " " + string(expr.Operator)
+	case Group:
+		parts := make([]string, len(expr.Exprs))
+		for i, child := range expr.Exprs {
+			parts[i] = describe(child)
+		}
+		kind := "and"
+		if expr.Or {
+			kind = "or"
+		}
+		return kind + "(" + strings.Join(parts, ", ") + ")"
+	}
+	return "<nil>"
+}
+
+func TestAndOr(t *testing.T) {
+	a, _ := testFields.Filter("id", "equals", "1")
+	b, _ := testFields.Filter("id", "equals", "2")
+	if And() != nil || And(nil, nil) != nil {
+		t.Error("And of nothing should be nil")
+	}
+	if got := describe(And(nil, a)); got != "id equals" {
+		t.Errorf("And(nil, a) = %s", got)
+	}
+	if got := describe(Or(a, nil, b)); got != "or(id equals, id equals)" {
+		t.Errorf("Or(a, nil, b) = %s", got)
+	}
+	if got := len(Conditions(And(a, Or(a, b)))); got != 3 {
+		t.Errorf("Conditions found %d, want 3", got)
+	}
+}
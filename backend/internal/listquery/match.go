@@ -0,0 +1,92 @@
+package listquery
+
+import (
+	"cmp"
+	"strings"
+	"time"
+)
+
+// Matches evaluates the condition against the record's value in the
+// field's column, with the same semantics as the SQL translation: missing
+// values only match IsNull.
+func (c Condition) Matches(value func(column string) any) bool {
+	v := value(c.Field.Column)
+	if c.Operator == IsNull {
+		return (v == nil) == c.Value.(bool)
+	}
+	if v == nil {
+		return false
+	}
+
+	if s, ok := v.(string); ok && !c.CaseSensitive {
+		v = strings.ToLower(s)
+	}
+	operand := c.foldCase(c.Value)
+	switch c.Operator {
+	case Equals, Between:
+		if c.Upper == nil {
+			return Compare(v, operand) == 0
+		}
+		upper := Compare(v, c.foldCase(c.Upper))
+		return Compare(v, operand) >= 0 && (upper < 0 || (upper == 0 && !c.ExcludeUpper))
+	case In:
+		for _, candidate := range c.Values {
+			if Compare(v, c.foldCase(candidate)) == 0 {
+				return true
+			}
+		}
+		return false
+	case Contains, StartsWith, EndsWith:
+		s, _ := v.(string)
+		pattern, _ := operand.(string)
+		switch c.Operator {
+		case Contains:
+			return strings.Contains(s, pattern)
+		case StartsWith:
+			return strings.HasPrefix(s, pattern)
+		default:
+			return strings.HasSuffix(s, pattern)
+		}
+	case Before:
+		return Compare(v, operand) < 0
+	case After:
+		return Compare(v, operand) > 0
+	}
+	return false
+}
+
+// foldCase lowercases string operands of case-insensitive conditions.
+func (c Condition) foldCase(operand any) any {
+	if s, ok := operand.(string); ok && !c.CaseSensitive {
+		return strings.ToLower(s)
+	}
+	return operand
+}
+
+// Compare orders two values of the same field type: strings, integers or
+// times. Integers of different Go types compare by value.
+func Compare(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	case time.Time:
+		b, _ := b.(time.Time)
+		return a.Compare(b)
+	}
+	return cmp.Compare(toInt64(a), toInt64(b))
+}
+
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+package listquery
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on a single filter, so a request cannot make queries arbitrarily
+// expensive.
+const (
+	MaxConditions = 20
+	MaxDepth      = 4
+)
+
+const (
+	filterParam = "filter"
+	groupAnd    = "and"
+	groupOr     = "or"
+)
+
+var filterKeyPattern = regexp.MustCompile(`^filter(\[[^\[\]]+\])+$`)
+
+// ParseFilter reads the filter[...] parameters of query into a tree of
+// validated conditions, or nil when there are none. Parameters combine
+// with AND:
+//
+//	filter[name][contains]=ann&filter[created_at][after]=2026-01-01
+//
+// Numbered members of filter[or] and filter[and] form groups, which may
+// nest:
+//
+//	filter[or][0][role][in]=admin,moderator&filter[or][1][name][equals:ci]=ann
+func (r *Registry) ParseFilter(query url.Values) (Expr, error) {
+	root := &filterNode{}
+	for key, values := range query {
+		if !strings.HasPrefix(key, filterParam+"[") {
+			continue
+		}
+		if !filterKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%w: malformed filter parameter %q", ErrInvalidQuery, key)
+		}
+		path := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, filterParam+"["), "]"), "][")
+		root.insert(path, values)
+	}
+	if len(root.children) == 0 {
+		return nil, nil
+	}
+
+	p := &filterParser{registry: r}
+	return p.group(root, false, 1)
+}
+
+// filterNode is one bracketed segment of the filter parameters.
+type filterNode struct {
+	children map[string]*filterNode
+	values   []string
+}
+
+func (n *filterNode) insert(path []string, values []string) {
+	if len(path) == 0 {
+		n.values = append(n.values, values...)
+		return
+	}
+	if n.children == nil {
+		n.children = make(map[string]*filterNode)
+	}
+	child, ok := n.children[path[0]]
+	if !ok {
+		child = &filterNode{}
+		n.children[path[0]] = child
+	}
+	child.insert(path[1:], values)
+}
+
+// sortedKeys returns the node's children in a stable order, numerically
+// for group members.
+func (n *filterNode) sortedKeys() []string {
+	keys := make([]string, 0, len(n.children))
+	for key := range n.children {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+type filterParser struct {
+	registry   *Registry
+	conditions int
+}
+
+// group reads node's fields and nested groups, combining them with OR or
+// AND.
+func (p *filterParser) group(node *filterNode, or bool, depth int) (Expr, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("%w: filter groups nest more than %d deep", ErrInvalidQuery, MaxDepth)
+	}
+	if len(node.values) > 0 {
+		return nil, fmt.Errorf("%w: filter groups take fields, e.g. filter[or][0][name][equals]", ErrInvalidQuery)
+	}
+
+	var exprs []Expr
+	for _, key := range node.sortedKeys() {
+		child := node.children[key]
+		switch key {
+		case groupAnd, groupOr:
+			members, err := p.members(child, key, depth)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, combine(key == groupOr, members))
+		default:
+			conditions, err := p.field(key, child)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, conditions...)
+		}
+	}
+	return combine(or, exprs), nil
+}
+
+// members reads the numbered members of an and/or group.
+func (p *filterParser) members(node *filterNode, kind string, depth int) ([]Expr, error) {
+	if len(node.values) > 0 || len(node.children) == 0 {
+		return nil, fmt.Errorf("%w: filter[%s] takes numbered members, e.g. filter[%s][0][name][equals]", ErrInvalidQuery, kind, kind)
+	}
+	var members []Expr
+	for _, key := range node.sortedKeys() {
+		if _, err := strconv.ParseUint(key, 10, 32); err != nil {
+			return nil, fmt.Errorf("%w: filter[%s] members must be numbered, got %q", ErrInvalidQuery, kind, key)
+		}
+		member, err := p.group(node.children[key], false, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// field reads the operators applied to the field called name. Repeating a
+// parameter adds another condition.
+func (p *filterParser) field(name string, node *filterNode) ([]Expr, error) {
+	if len(node.values) > 0 || len(node.children) == 0 {
+		return nil, fmt.Errorf("%w: filter[%s] needs an operator, e.g. filter[%s][equals]", ErrInvalidQuery, name, name)
+	}
+	var conditions []Expr
+	for _, operator := range node.sortedKeys() {
+		child := node.children[operator]
+		if len(child.children) > 0 {
+			return nil, fmt.Errorf("%w: unexpected parameter below filter[%s][%s]", ErrInvalidQuery, name, operator)
+		}
+		for _, raw := range child.values {
+			p.conditions++
+			if p.conditions > MaxConditions {
+				return nil, fmt.Errorf("%w: at most %d filter conditions are allowed", ErrInvalidQuery, MaxConditions)
+			}
+			condition, err := p.registry.Filter(name, operator, raw)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions, nil
+}
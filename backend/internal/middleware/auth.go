@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"main/internal/models"
+	"main/internal/utils"
+	"main/pkg/database"
+)
+
+type contextKey string
+
+const (
+	userContextKey    contextKey = "user"
+	sessionContextKey contextKey = "session"
+)
+
+// lastSeenResolution limits how often a session's last_seen_at is written.
+const lastSeenResolution = time.Minute
+
+// Authenticate requires a valid bearer token and stores the caller and their
+// session in the request context.
+func Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflights never carry credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			unauthorized(w, "Missing bearer token")
+			return
+		}
+
+		session, err := lookupSession(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, utils.ErrInvalidCredentials) {
+				unauthorized(w, "Invalid or expired token")
+				return
+			}
+			utils.SendErrorResponse(w, "Could not verify token", http.StatusInternalServerError)
+			return
+		}
+
+		ctx := ContextWithUser(r.Context(), session.User)
+		ctx = context.WithValue(ctx, sessionContextKey, session)
+		ctx = utils.ContextWithLogger(ctx, utils.LoggerFromContext(ctx).WithField("user_id", session.User.UserID))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// RequireVerifiedEmail rejects callers who have not confirmed their email
+// address. It must run after Authenticate.
+func RequireVerifiedEmail(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authentication required")
+			return
+		}
+		if !user.IsEmailVerified() {
+			utils.SendErrorResponse(w, "Please verify your email address first", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// ContextWithUser stores user as the authenticated caller. Authenticate uses
+// it after checking the session; tests can use it to call handlers directly.
+func ContextWithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the authenticated caller, if any.
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userContextKey).(*models.User)
+	return user, ok && user != nil
+}
+
+// SessionFromContext returns the session the caller authenticated with, if any.
+func SessionFromContext(ctx context.Context) (*models.Session, bool) {
+	session, ok := ctx.Value(sessionContextKey).(*models.Session)
+	return session, ok && session != nil
+}
+
+func lookupSession(ctx context.Context, token string) (*models.Session, error) {
+	now := time.Now().UTC()
+
+	var session models.Session
+	err := database.DB.WithContext(ctx).Preload("User").
+		Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", utils.HashToken(token), now).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if session.User == nil {
+		return nil, utils.ErrInvalidCredentials
+	}
+
+	if now.Sub(session.LastSeenAt) > lastSeenResolution {
+		session.LastSeenAt = now
+		database.DB.WithContext(ctx).Model(&session).Update("last_seen_at", now)
+	}
+
+	return &session, nil
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	utils.SendErrorResponse(w, message, http.StatusUnauthorized)
+}
@@ -1,35 +1,148 @@
 package middleware
 
 import (
-	"context"
-	"golang.org/x/time/rate"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var limiter = rate.NewLimiter(1, 3)
+// CORSOptions configures which cross-origin requests browsers may make.
+type CORSOptions struct {
+	// AllowedOrigins holds exact origins such as https://app.example.com,
+	// wildcard subdomain patterns such as https://*.example.com, or "*" to
+	// allow any origin without credentials.
+	AllowedOrigins []string
+	// AllowedMethods are answered to preflights of routes that do not list
+	// their own methods.
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge lets browsers cache preflight responses.
+	MaxAge time.Duration
+}
 
-func CORS(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, x-ijt, X-Requested-With, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
+type corsPolicy struct {
+	options   CORSOptions
+	anyOrigin bool
+	exact     map[string]bool
+	wildcards []wildcardOrigin
+}
+
+// wildcardOrigin matches any subdomain of host, but not host itself.
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+}
+
+// cors rejects every cross-origin request until SetCORSOptions is called.
+var cors = newCORSPolicy(CORSOptions{})
+
+// SetCORSOptions replaces the CORS policy. It must be called before the
+// server starts handling requests.
+func SetCORSOptions(options CORSOptions) {
+	cors = newCORSPolicy(options)
+}
+
+func newCORSPolicy(options CORSOptions) *corsPolicy {
+	policy := &corsPolicy{options: options, exact: map[string]bool{}}
+	for _, origin := range options.AllowedOrigins {
+		origin = strings.TrimRight(strings.ToLower(strings.TrimSpace(origin)), "/")
+		switch {
+		case origin == "*":
+			// Reflecting any origin with credentials would let every site
+			// act as the user; config validation rejects it, and so does
+			// the policy in case it is built some other way
+			policy.anyOrigin = !options.AllowCredentials
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*.")
+			policy.wildcards = append(policy.wildcards, wildcardOrigin{scheme: scheme, suffix: "." + host})
+		case origin != "":
+			policy.exact[origin] = true
 		}
+	}
+	return policy
+}
 
-		next.ServeHTTP(w, r)
+func (p *corsPolicy) allows(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
 	}
+
+	// An origin is only a scheme and host, e.g. https://app.example.com
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" {
+		return false
+	}
+	for _, wildcard := range p.wildcards {
+		if u.Scheme == wildcard.scheme && strings.HasSuffix(u.Host, wildcard.suffix) && len(u.Host) > len(wildcard.suffix) {
+			return true
+		}
+	}
+	return false
 }
 
-func RateLimiter(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if err := limiter.Wait(context.Background()); err != nil {
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
-			return
+// CORS applies the configured policy, answering preflights with the
+// globally allowed methods.
+func CORS(next http.HandlerFunc) http.HandlerFunc {
+	return CORSForMethods(nil)(next)
+}
+
+// CORSForMethods applies the configured policy, answering preflights with
+// the methods methodsFor reports for the requested route. A nil methodsFor
+// falls back to CORSOptions.AllowedMethods.
+func CORSForMethods(methodsFor func(r *http.Request) []string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			policy := cors
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// Responses differ per origin, so caches must not share them
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			allowed := origin != "" && policy.allows(origin)
+			if allowed {
+				if policy.anyOrigin && !policy.options.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+				if policy.options.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if r.Method == http.MethodOptions {
+				if preflight && allowed {
+					methods := policy.options.AllowedMethods
+					if methodsFor != nil {
+						methods = methodsFor(r)
+					}
+					w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+					w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.options.AllowedHeaders, ", "))
+					if policy.options.MaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.options.MaxAge.Seconds())))
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if allowed && len(policy.options.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.options.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
 		}
-		next.ServeHTTP(w, r)
 	}
 }
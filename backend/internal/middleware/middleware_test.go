@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORSPolicyAllows(t *testing.T) {
+	policy := newCORSPolicy(CORSOptions{
+		AllowedOrigins: []string{" https://App.Example.com/ ", "https://*.example.com", "http://localhost:3000"},
+	})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://localhost:3000", true},
+		{"https://a.example.com", true},
+		{"https://a.b.example.com", true},
+
+		// The wildcard covers subdomains only, over the configured scheme
+		{"https://example.com", false},
+		{"http://a.example.com", false},
+		{"https://a.example.com:8443", false},
+
+		// Lookalikes
+		{"https://evilexample.com", false},
+		{"https://a.evilexample.com", false},
+		{"https://a.example.com.evil.io", false},
+		{"https://example.com.evil.io", false},
+		{"https://evil.io/.example.com", false},
+		{"https://evil.io?.example.com", false},
+		{"https://user@a.example.com", false},
+		{"https://app.example.com.", false},
+		{"http://localhost:3001", false},
+		{"http://localhost", false},
+
+		{"null", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := policy.allows(tt.origin); got != tt.want {
+			t.Errorf("allows(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+// corsRequest sends method with origin through a CORS chain ending in a
+// handler that answers 200, with Access-Control-Request-Method set for
+// preflights.
+func corsRequest(t *testing.T, options CORSOptions, chain func(http.HandlerFunc) http.HandlerFunc, method, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+	previous := cors
+	SetCORSOptions(options)
+	t.Cleanup(func() { cors = previous })
+
+	r := httptest.NewRequest(method, "/api/v1/posts", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	w := httptest.NewRecorder()
+	chain(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})(w, r)
+	return w
+}
+
+var appOrigins = CORSOptions{
+	AllowedOrigins:   []string{"https://app.example.com"},
+	AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+	AllowedHeaders:   []string{"Content-Type", "Authorization"},
+	ExposedHeaders:   []string{"X-Request-ID"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	w := corsRequest(t, appOrigins, CORS, http.MethodGet, "https://app.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Expose-Headers":    "X-Request-ID",
+		"Vary":                             "Origin",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		w := corsRequest(t, appOrigins, CORS, method, "https://evil.example.org")
+		for name := range w.Header() {
+			if name != "Vary" {
+				t.Errorf("%s: disallowed origin got header %s: %q", method, name, w.Header().Get(name))
+			}
+		}
+	}
+
+	// Same-origin and non-browser requests pass through untouched
+	w := corsRequest(t, appOrigins, CORS, http.MethodGet, "")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("request without Origin: status %d, headers %v", w.Code, w.Header())
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	options := CORSOptions{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}
+	w := corsRequest(t, options, CORS, http.MethodGet, "https://anywhere.example.org")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("credentials allowed for any origin: %q", got)
+	}
+}
+
+func TestCORSRefusesAnyOriginWithCredentials(t *testing.T) {
+	options := CORSOptions{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowCredentials: true}
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		w := corsRequest(t, options, CORS, method, "https://evil.example.org")
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", method, got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Credentials = %q, want none", method, got)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	methodsFor := func(r *http.Request) []string { return []string{"GET", "POST", "OPTIONS"} }
+	w := corsRequest(t, appOrigins, CORSForMethods(methodsFor), http.MethodOptions, "https://app.example.com")
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204 without reaching the handler", w.Code)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Max-Age":       "600",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 3 {
+		t.Errorf("Vary = %v, want Origin and both request headers", vary)
+	}
+
+	// Without route methods the global list is answered
+	w = corsRequest(t, appOrigins, CORS, http.MethodOptions, "https://app.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, PATCH, DELETE" {
+		t.Errorf("global Access-Control-Allow-Methods = %q", got)
+	}
+}
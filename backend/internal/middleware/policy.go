@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+
+	"main/internal/models"
+	"main/internal/utils"
+)
+
+type Permission string
+
+const (
+	// PermViewUserDetails allows reading private fields such as email.
+	PermViewUserDetails Permission = "users:view_details"
+	// PermManageUsers allows editing accounts other than one's own.
+	PermManageUsers Permission = "users:manage"
+	// PermDeleteUsers allows hard-deleting accounts other than one's own.
+	PermDeleteUsers Permission = "users:delete"
+	// PermManageRoles allows granting and revoking roles.
+	PermManageRoles Permission = "users:manage_roles"
+	// PermModeratePosts allows editing and removing other users' posts.
+	PermModeratePosts Permission = "posts:moderate"
+)
+
+var rolePermissions = map[string][]Permission{
+	models.RoleUser: {},
+	models.RoleModerator: {
+		PermViewUserDetails,
+		PermModeratePosts,
+	},
+	models.RoleAdmin: {
+		PermViewUserDetails,
+		PermManageUsers,
+		PermDeleteUsers,
+		PermManageRoles,
+		PermModeratePosts,
+	},
+}
+
+// Can reports whether user's role grants perm.
+func Can(user *models.User, perm Permission) bool {
+	if user == nil {
+		return false
+	}
+	for _, granted := range rolePermissions[user.Role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize rejects callers whose role lacks perm. It must run after
+// Authenticate.
+func Authorize(perm Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authentication required")
+			return
+		}
+		if !Can(user, perm) {
+			utils.SendErrorResponse(w, "You do not have permission to perform this action", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
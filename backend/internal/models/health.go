@@ -0,0 +1,10 @@
+package models
+
+// DependencyStatus is the outcome of one readiness check.
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	// Critical dependencies make the service unready when they fail.
+	Critical bool `json:"critical"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a single-use fallback for a user who lost their
+// authenticator. Only the hash of the code is stored.
+type RecoveryCode struct {
+	RecoveryCodeID uint       `gorm:"primaryKey;column:recovery_code_id"`
+	UserID         uint       `gorm:"column:user_id;not null;index"`
+	User           *User      `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE"`
+	CodeHash       string     `gorm:"column:code_hash;not null"`
+	UsedAt         *time.Time `gorm:"column:used_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// MFAChallenge is returned by login instead of tokens when the account has
+// two-factor authentication enabled.
+type MFAChallenge struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
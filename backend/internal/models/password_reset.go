@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+type PasswordResetToken struct {
+	PasswordResetTokenID uint       `gorm:"primaryKey;column:password_reset_token_id"`
+	UserID               uint       `gorm:"column:user_id;not null;index"`
+	User                 *User      `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE"`
+	TokenHash            string     `gorm:"column:token_hash;uniqueIndex;not null"`
+	ExpiresAt            time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt               *time.Time `gorm:"column:used_at"`
+	CreatedAt            time.Time  `gorm:"column:created_at"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+type Post struct {
+	PostID    uint      `gorm:"primaryKey;column:post_id" json:"post_id"`
+	UserID    uint      `gorm:"column:user_id;not null;index" json:"user_id"`
+	Author    *User     `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Body      string    `gorm:"column:body;type:text;not null" json:"body"`
+	CreatedAt time.Time `gorm:"column:created_at;index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (Post) TableName() string {
+	return "posts"
+}
+
+// PostResponse is a post as returned by the API, exposing only the public
+// fields of its author.
+type PostResponse struct {
+	PostID    uint        `json:"post_id"`
+	UserID    uint        `json:"user_id"`
+	Author    *PublicUser `json:"author,omitempty"`
+	Body      string      `json:"body"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func (p Post) Response() PostResponse {
+	response := PostResponse{PostID: p.PostID, UserID: p.UserID, Body: p.Body, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt}
+	if p.Author != nil {
+		author := p.Author.Public()
+		response.Author = &author
+	}
+	return response
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one signed-in device. TokenHash and ExpiresAt describe the
+// current short-lived access token; RefreshExpiresAt is when the device
+// stops being able to obtain new ones.
+type Session struct {
+	SessionID        uint       `gorm:"primaryKey;column:session_id" json:"session_id"`
+	UserID           uint       `gorm:"column:user_id;not null;index" json:"user_id"`
+	User             *User      `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	TokenHash        string     `gorm:"column:token_hash;uniqueIndex;not null" json:"-"`
+	UserAgent        string     `gorm:"column:user_agent" json:"user_agent"`
+	IPAddress        string     `gorm:"column:ip_address" json:"ip_address"`
+	ExpiresAt        time.Time  `gorm:"column:expires_at;not null" json:"-"`
+	RefreshExpiresAt time.Time  `gorm:"column:refresh_expires_at" json:"expires_at"`
+	LastSeenAt       time.Time  `gorm:"column:last_seen_at" json:"last_seen_at"`
+	RevokedAt        *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"column:created_at" json:"created_at"`
+	Current          bool       `gorm:"-" json:"current"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// RefreshToken is a single-use credential belonging to a session. Every
+// refresh marks the presented token used and issues its successor, so a used
+// token showing up again means it was stolen.
+type RefreshToken struct {
+	RefreshTokenID uint       `gorm:"primaryKey;column:refresh_token_id"`
+	SessionID      uint       `gorm:"column:session_id;not null;index"`
+	Session        *Session   `gorm:"foreignKey:SessionID;references:SessionID;constraint:OnDelete:CASCADE"`
+	TokenHash      string     `gorm:"column:token_hash;uniqueIndex;not null"`
+	ExpiresAt      time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt         *time.Time `gorm:"column:used_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+type AuthResponse struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             User      `json:"user"`
+}
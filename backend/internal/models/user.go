@@ -4,22 +4,59 @@ import (
 	"time"
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
-	UserID    uint      `gorm:"primaryKey;column:user_id" json:"user_id"`
-	UserName  string    `gorm:"column:user_name" json:"user_name"`
-	UserEmail string    `gorm:"column:user_email;uniqueIndex" json:"user_email"`
-	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+	UserID          uint       `gorm:"primaryKey;column:user_id" json:"user_id"`
+	UserName        string     `gorm:"column:user_name" json:"user_name"`
+	UserEmail       string     `gorm:"column:user_email;uniqueIndex" json:"user_email"`
+	PasswordHash    string     `gorm:"column:password_hash" json:"-"`
+	Role            string     `gorm:"column:role;not null;default:user" json:"role"`
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at" json:"email_verified_at,omitempty"`
+	TOTPEnabled     bool       `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled"`
+	TOTPSecret      string     `gorm:"column:totp_secret" json:"-"`
+	TOTPLastStep    int64      `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+	CreatedAt       time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at" json:"updated_at"`
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
+func (u User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// PublicUser is the subset of a user record visible to any caller.
+type PublicUser struct {
+	UserID    uint      `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (u User) Public() PublicUser {
+	return PublicUser{UserID: u.UserID, UserName: u.UserName, CreatedAt: u.CreatedAt}
+}
+
 type ResponseData struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Status    string      `json:"status"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 type PaginatedResponse struct {
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"main/internal/listquery"
+	"main/internal/models"
+	"main/internal/utils"
+)
+
+// GormUserRepository stores users in the database behind db.
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) Create(ctx context.Context, user *models.User) error {
+	userTimesToUTC(user)
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		if utils.IsDuplicateEmailError(err) {
+			return utils.ErrDuplicateEmail
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *GormUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("user_email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) List(ctx context.Context, opts UserListOptions) ([]models.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.User{}).Scopes(listquery.Where(opts.Filter))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Scopes run when the query executes, after any Order added here, so the
+	// ID tiebreak has to follow the requested sort explicitly
+	query = listquery.OrderBy(opts.Sort...)(query).Order("user_id asc")
+
+	var users []models.User
+	if err := query.Offset(opts.Offset).Limit(opts.Limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *GormUserRepository) Update(ctx context.Context, user *models.User) error {
+	userTimesToUTC(user)
+	result := r.db.WithContext(ctx).Model(user).
+		Select("user_name", "user_email", "email_verified_at", "updated_at").
+		Updates(user)
+	if result.Error != nil {
+		if utils.IsDuplicateEmailError(result.Error) {
+			return utils.ErrDuplicateEmail
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *GormUserRepository) UpdateRole(ctx context.Context, user *models.User) error {
+	userTimesToUTC(user)
+	return r.db.WithContext(ctx).Model(user).Select("role", "updated_at").Updates(user).Error
+}
+
+func (r *GormUserRepository) UpdateEmailVerified(ctx context.Context, user *models.User) error {
+	return r.updateColumns(ctx, user, "email_verified_at", "updated_at")
+}
+
+func (r *GormUserRepository) UpdatePassword(ctx context.Context, user *models.User) error {
+	return r.updateColumns(ctx, user, "password_hash", "updated_at")
+}
+
+func (r *GormUserRepository) UpdateTOTP(ctx context.Context, user *models.User) error {
+	return r.updateColumns(ctx, user, "totp_enabled", "totp_secret", "totp_last_step", "updated_at")
+}
+
+// updateColumns saves the named columns of user, including zero values.
+func (r *GormUserRepository) updateColumns(ctx context.Context, user *models.User, columns ...string) error {
+	userTimesToUTC(user)
+	result := r.db.WithContext(ctx).Model(user).Select(columns).Updates(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *GormUserRepository) RecordTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("user_id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormUserRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrUserNotFound
+	}
+	return nil
+}
+
+// GormPostRepository stores posts in the database behind db.
+type GormPostRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPostRepository(db *gorm.DB) *GormPostRepository {
+	return &GormPostRepository{db: db}
+}
+
+// Create inserts post after checking that its author exists.
+func (r *GormPostRepository) Create(ctx context.Context, post *models.Post) error {
+	var author models.User
+	if err := r.db.WithContext(ctx).First(&author, post.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.ErrUserNotFound
+		}
+		return err
+	}
+
+	postTimesToUTC(post)
+	if err := r.db.WithContext(ctx).Create(post).Error; err != nil {
+		return err
+	}
+	post.Author = &author
+	return nil
+}
+
+func (r *GormPostRepository) FindByID(ctx context.Context, id uint) (*models.Post, error) {
+	var post models.Post
+	if err := r.db.WithContext(ctx).Preload("Author").First(&post, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.ErrPostNotFound
+		}
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (r *GormPostRepository) List(ctx context.Context, opts PostListOptions) ([]models.Post, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Post{}).Scopes(listquery.Where(opts.Filter))
+	if opts.AuthorID != 0 {
+		query = query.Where("user_id = ?", opts.AuthorID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	err := query.Preload("Author").
+		Order("created_at desc").Order("post_id desc").
+		Offset(opts.Offset).Limit(opts.Limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+func (r *GormPostRepository) UpdateBody(ctx context.Context, post *models.Post) error {
+	postTimesToUTC(post)
+	return r.db.WithContext(ctx).Model(post).Select("body", "updated_at").Updates(post).Error
+}
+
+func (r *GormPostRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Post{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrPostNotFound
+	}
+	return nil
+}
+
+// userTimesToUTC converts the timestamps about to be written to UTC. SQLite
+// compares times as text, so rows written with differing offsets would not
+// filter or order correctly against listquery values, which are in UTC.
+func userTimesToUTC(user *models.User) {
+	user.CreatedAt = user.CreatedAt.UTC()
+	user.UpdatedAt = user.UpdatedAt.UTC()
+	if user.EmailVerifiedAt != nil {
+		verified := user.EmailVerifiedAt.UTC()
+		user.EmailVerifiedAt = &verified
+	}
+}
+
+// postTimesToUTC is userTimesToUTC for posts.
+func postTimesToUTC(post *models.Post) {
+	post.CreatedAt = post.CreatedAt.UTC()
+	post.UpdatedAt = post.UpdatedAt.UTC()
+}
@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"main/internal/listquery"
+	"main/internal/models"
+	"main/internal/utils"
+)
+
+// MemoryUserRepository keeps users in process. It is meant for tests and
+// local experiments; nothing survives a restart.
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[uint]models.User
+	nextID uint
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[uint]models.User), nextID: 1}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.UserEmail == user.UserEmail {
+			return utils.ErrDuplicateEmail
+		}
+	}
+
+	if user.UserID == 0 {
+		user.UserID = r.nextID
+	} else if _, taken := r.users[user.UserID]; taken {
+		return fmt.Errorf("user %d already exists", user.UserID)
+	}
+	if user.UserID >= r.nextID {
+		r.nextID = user.UserID + 1
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	r.users[user.UserID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, utils.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.UserEmail == email {
+			return &user, nil
+		}
+	}
+	return nil, utils.ErrUserNotFound
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, opts UserListOptions) ([]models.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := make([]models.User, 0, len(r.users))
+	for _, user := range r.users {
+		if meetsFilter(opts.Filter, func(column string) any { return userColumn(user, column) }) {
+			matches = append(matches, user)
+		}
+	}
+
+	// Ties fall back to the ID, as in GormUserRepository
+	sort.Slice(matches, func(i, j int) bool {
+		for _, s := range opts.Sort {
+			c := listquery.Compare(userColumn(matches[i], s.Field.Column), userColumn(matches[j], s.Field.Column))
+			if c != 0 {
+				return (c < 0) != s.Desc
+			}
+		}
+		return matches[i].UserID < matches[j].UserID
+	})
+	return page(matches, opts.Offset, opts.Limit), int64(len(matches)), nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.UserID]
+	if !ok {
+		return utils.ErrUserNotFound
+	}
+	for id, existing := range r.users {
+		if id != user.UserID && existing.UserEmail == user.UserEmail {
+			return utils.ErrDuplicateEmail
+		}
+	}
+	stored.UserName = user.UserName
+	stored.UserEmail = user.UserEmail
+	stored.EmailVerifiedAt = user.EmailVerifiedAt
+	stored.UpdatedAt = user.UpdatedAt
+	r.users[user.UserID] = stored
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateRole(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.UserID]
+	if !ok {
+		return utils.ErrUserNotFound
+	}
+	stored.Role = user.Role
+	stored.UpdatedAt = user.UpdatedAt
+	r.users[user.UserID] = stored
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateEmailVerified(ctx context.Context, user *models.User) error {
+	return r.update(user.UserID, func(stored *models.User) {
+		stored.EmailVerifiedAt = user.EmailVerifiedAt
+		stored.UpdatedAt = user.UpdatedAt
+	})
+}
+
+func (r *MemoryUserRepository) UpdatePassword(ctx context.Context, user *models.User) error {
+	return r.update(user.UserID, func(stored *models.User) {
+		stored.PasswordHash = user.PasswordHash
+		stored.UpdatedAt = user.UpdatedAt
+	})
+}
+
+func (r *MemoryUserRepository) UpdateTOTP(ctx context.Context, user *models.User) error {
+	return r.update(user.UserID, func(stored *models.User) {
+		stored.TOTPEnabled = user.TOTPEnabled
+		stored.TOTPSecret = user.TOTPSecret
+		stored.TOTPLastStep = user.TOTPLastStep
+		stored.UpdatedAt = user.UpdatedAt
+	})
+}
+
+func (r *MemoryUserRepository) RecordTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	recorded := false
+	err := r.update(id, func(stored *models.User) {
+		if stored.TOTPLastStep < step {
+			stored.TOTPLastStep = step
+			recorded = true
+		}
+	})
+	if errors.Is(err, utils.ErrUserNotFound) {
+		return false, nil
+	}
+	return recorded, err
+}
+
+// update applies change to the stored user id under the write lock.
+func (r *MemoryUserRepository) update(id uint, change func(stored *models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[id]
+	if !ok {
+		return utils.ErrUserNotFound
+	}
+	change(&stored)
+	r.users[id] = stored
+	return nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return utils.ErrUserNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// userColumn returns the value stored in column for user.
+func userColumn(user models.User, column string) any {
+	switch column {
+	case "user_id":
+		return user.UserID
+	case "user_name":
+		return user.UserName
+	case "user_email":
+		return user.UserEmail
+	case "role":
+		return user.Role
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	case "email_verified_at":
+		if user.EmailVerifiedAt == nil {
+			return nil
+		}
+		return *user.EmailVerifiedAt
+	}
+	panic("repository: unknown users column " + column)
+}
+
+// MemoryPostRepository keeps posts in process and loads their authors from
+// users. Deleting a user does not cascade to their posts.
+type MemoryPostRepository struct {
+	users  UserRepository
+	mu     sync.RWMutex
+	posts  map[uint]models.Post
+	nextID uint
+}
+
+func NewMemoryPostRepository(users UserRepository) *MemoryPostRepository {
+	return &MemoryPostRepository{users: users, posts: make(map[uint]models.Post), nextID: 1}
+}
+
+// Create inserts post after checking that its author exists.
+func (r *MemoryPostRepository) Create(ctx context.Context, post *models.Post) error {
+	author, err := r.users.FindByID(ctx, post.UserID)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post.PostID = r.nextID
+	r.nextID++
+	post.Author = nil
+	r.posts[post.PostID] = *post
+	post.Author = author
+	return nil
+}
+
+func (r *MemoryPostRepository) FindByID(ctx context.Context, id uint) (*models.Post, error) {
+	r.mu.RLock()
+	post, ok := r.posts[id]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, utils.ErrPostNotFound
+	}
+	r.loadAuthor(ctx, &post)
+	return &post, nil
+}
+
+func (r *MemoryPostRepository) List(ctx context.Context, opts PostListOptions) ([]models.Post, int64, error) {
+	r.mu.RLock()
+	matches := make([]models.Post, 0, len(r.posts))
+	for _, post := range r.posts {
+		if (opts.AuthorID == 0 || post.UserID == opts.AuthorID) &&
+			meetsFilter(opts.Filter, func(column string) any { return postColumn(post, column) }) {
+			matches = append(matches, post)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].PostID > matches[j].PostID
+	})
+
+	posts := page(matches, opts.Offset, opts.Limit)
+	for i := range posts {
+		r.loadAuthor(ctx, &posts[i])
+	}
+	return posts, int64(len(matches)), nil
+}
+
+func (r *MemoryPostRepository) UpdateBody(ctx context.Context, post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.posts[post.PostID]
+	if !ok {
+		return utils.ErrPostNotFound
+	}
+	stored.Body = post.Body
+	stored.UpdatedAt = post.UpdatedAt
+	r.posts[post.PostID] = stored
+	return nil
+}
+
+func (r *MemoryPostRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.posts[id]; !ok {
+		return utils.ErrPostNotFound
+	}
+	delete(r.posts, id)
+	return nil
+}
+
+func (r *MemoryPostRepository) loadAuthor(ctx context.Context, post *models.Post) {
+	if author, err := r.users.FindByID(ctx, post.UserID); err == nil {
+		post.Author = author
+	}
+}
+
+// postColumn returns the value stored in column for post.
+func postColumn(post models.Post, column string) any {
+	switch column {
+	case "post_id":
+		return post.PostID
+	case "user_id":
+		return post.UserID
+	case "body":
+		return post.Body
+	case "created_at":
+		return post.CreatedAt
+	case "updated_at":
+		return post.UpdatedAt
+	}
+	panic("repository: unknown posts column " + column)
+}
+
+// meetsFilter reports whether a record whose columns column returns meets
+// filter, which may be nil.
+func meetsFilter(filter listquery.Expr, column func(string) any) bool {
+	return filter == nil || filter.Matches(column)
+}
+
+// page returns the items between offset and offset+limit. A non-positive
+// limit returns everything after offset.
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[max(offset, 0):]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
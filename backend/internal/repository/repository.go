@@ -0,0 +1,72 @@
+// Package repository abstracts persistence of users and posts so handlers
+// can run against Postgres through GORM or against in-memory stores in tests.
+// The GORM repositories write every timestamp in UTC.
+package repository
+
+import (
+	"context"
+
+	"main/internal/listquery"
+	"main/internal/models"
+)
+
+// UserListOptions selects one page of users. Filter and Sort are built
+// from a listquery.Registry; without Sort users are ordered by ID.
+type UserListOptions struct {
+	Filter listquery.Expr
+	Sort   []listquery.Sort
+	Offset int
+	Limit  int
+}
+
+// UserRepository stores user accounts. Lookups of missing users return
+// utils.ErrUserNotFound and inserting a taken email returns
+// utils.ErrDuplicateEmail. Implementations must be safe for concurrent use.
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	// FindByEmail looks up the account registered under a normalized email.
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// List returns the requested page and the number of users matching the
+	// filter across all pages.
+	List(ctx context.Context, opts UserListOptions) ([]models.User, int64, error)
+	// Update saves only the profile fields user.UserName, user.UserEmail,
+	// user.EmailVerifiedAt and user.UpdatedAt, so concurrent password, TOTP
+	// or role changes are kept.
+	Update(ctx context.Context, user *models.User) error
+	// UpdateRole saves only user.Role and user.UpdatedAt.
+	UpdateRole(ctx context.Context, user *models.User) error
+	// UpdateEmailVerified saves only user.EmailVerifiedAt and user.UpdatedAt.
+	UpdateEmailVerified(ctx context.Context, user *models.User) error
+	// UpdatePassword saves only user.PasswordHash and user.UpdatedAt.
+	UpdatePassword(ctx context.Context, user *models.User) error
+	// UpdateTOTP saves only user.TOTPEnabled, user.TOTPSecret,
+	// user.TOTPLastStep and user.UpdatedAt.
+	UpdateTOTP(ctx context.Context, user *models.User) error
+	// RecordTOTPStep stores step as the last TOTP step used by user id if
+	// it is later than the stored one and reports whether it was, so two
+	// requests racing with the same code cannot both succeed.
+	RecordTOTPStep(ctx context.Context, id uint, step int64) (bool, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+// PostListOptions selects one page of posts, newest first. A zero AuthorID
+// lists posts by every author; Filter is built from a listquery.Registry.
+type PostListOptions struct {
+	AuthorID uint
+	Filter   listquery.Expr
+	Offset   int
+	Limit    int
+}
+
+// PostRepository stores posts. Posts are returned with their Author loaded
+// and lookups of missing posts return utils.ErrPostNotFound.
+// Implementations must be safe for concurrent use.
+type PostRepository interface {
+	Create(ctx context.Context, post *models.Post) error
+	FindByID(ctx context.Context, id uint) (*models.Post, error)
+	List(ctx context.Context, opts PostListOptions) ([]models.Post, int64, error)
+	// UpdateBody saves only post.Body and post.UpdatedAt.
+	UpdateBody(ctx context.Context, post *models.Post) error
+	Delete(ctx context.Context, id uint) error
+}
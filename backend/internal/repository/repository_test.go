@@ -0,0 +1,504 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"main/internal/listquery"
+	"main/internal/models"
+	"main/internal/utils"
+	"main/pkg/database/dbtest"
+)
+
+// backends runs test against the GORM repositories on a temporary SQLite
+// database and against the in-memory ones, which must behave alike.
+func backends(t *testing.T, test func(t *testing.T, users UserRepository, posts PostRepository)) {
+	t.Run("sqlite", func(t *testing.T) {
+		db := dbtest.Open(t)
+		test(t, NewGormUserRepository(db), NewGormPostRepository(db))
+	})
+	t.Run("memory", func(t *testing.T) {
+		users := NewMemoryUserRepository()
+		test(t, users, NewMemoryPostRepository(users))
+	})
+}
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func createUser(t *testing.T, users UserRepository, name string, day int) *models.User {
+	t.Helper()
+	created := testTime.AddDate(0, 0, day)
+	user := &models.User{
+		UserName:     name,
+		UserEmail:    name + "@example.com",
+		PasswordHash: "hash",
+		Role:         models.RoleUser,
+		CreatedAt:    created,
+		UpdatedAt:    created,
+	}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestUsers(t *testing.T) {
+	backends(t, func(t *testing.T, users UserRepository, _ PostRepository) {
+		ctx := context.Background()
+		anna := createUser(t, users, "anna", 0)
+		bob := createUser(t, users, "bob", 1)
+		if anna.UserID == 0 || bob.UserID == anna.UserID {
+			t.Fatalf("IDs not assigned: %d, %d", anna.UserID, bob.UserID)
+		}
+
+		duplicate := &models.User{UserName: "other", UserEmail: "anna@example.com", Role: models.RoleUser}
+		if err := users.Create(ctx, duplicate); !errors.Is(err, utils.ErrDuplicateEmail) {
+			t.Errorf("Create with a taken email: err = %v, want ErrDuplicateEmail", err)
+		}
+
+		found, err := users.FindByID(ctx, anna.UserID)
+		if err != nil || found.UserEmail != "anna@example.com" {
+			t.Fatalf("FindByID = %+v, %v", found, err)
+		}
+		if _, err := users.FindByID(ctx, 9999); !errors.Is(err, utils.ErrUserNotFound) {
+			t.Errorf("FindByID of a missing user: err = %v, want ErrUserNotFound", err)
+		}
+
+		found.UserEmail = "bob@example.com"
+		if err := users.Update(ctx, found); !errors.Is(err, utils.ErrDuplicateEmail) {
+			t.Errorf("Update to a taken email: err = %v, want ErrDuplicateEmail", err)
+		}
+
+		bob.Role = models.RoleModerator
+		if err := users.UpdateRole(ctx, bob); err != nil {
+			t.Fatal(err)
+		}
+		if found, _ := users.FindByID(ctx, bob.UserID); found.Role != models.RoleModerator {
+			t.Errorf("role after UpdateRole = %q", found.Role)
+		}
+
+		if err := users.Delete(ctx, bob.UserID); err != nil {
+			t.Fatal(err)
+		}
+		if err := users.Delete(ctx, bob.UserID); !errors.Is(err, utils.ErrUserNotFound) {
+			t.Errorf("second Delete: err = %v, want ErrUserNotFound", err)
+		}
+	})
+}
+
+// TestUserUpdateKeepsOtherColumns checks that a profile update does not
+// overwrite a password or TOTP change made after the user was loaded.
+func TestUserUpdateKeepsOtherColumns(t *testing.T) {
+	backends(t, func(t *testing.T, users UserRepository, _ PostRepository) {
+		ctx := context.Background()
+		anna := createUser(t, users, "anna", 0)
+
+		loaded, err := users.FindByID(ctx, anna.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		anna.Role = models.RoleAdmin
+		if err := users.UpdateRole(ctx, anna); err != nil {
+			t.Fatal(err)
+		}
+
+		verified := testTime
+		loaded.UserName = "Anna"
+		loaded.UserEmail = "anna.new@example.com"
+		loaded.EmailVerifiedAt = &verified
+		loaded.UpdatedAt = testTime.Add(time.Hour)
+		loaded.Role = models.RoleUser
+		loaded.PasswordHash = "stale"
+		if err := users.Update(ctx, loaded); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := users.FindByID(ctx, anna.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.UserName != "Anna" || stored.UserEmail != "anna.new@example.com" || stored.EmailVerifiedAt == nil {
+			t.Errorf("profile fields not saved: %+v", stored)
+		}
+		if stored.Role != models.RoleAdmin || stored.PasswordHash != "hash" {
+			t.Errorf("Update overwrote role %q or password %q", stored.Role, stored.PasswordHash)
+		}
+	})
+}
+
+func TestUserFindByEmail(t *testing.T) {
+	backends(t, func(t *testing.T, users UserRepository, _ PostRepository) {
+		ctx := context.Background()
+		anna := createUser(t, users, "anna", 0)
+
+		found, err := users.FindByEmail(ctx, "anna@example.com")
+		if err != nil || found.UserID != anna.UserID {
+			t.Fatalf("FindByEmail = %+v, %v", found, err)
+		}
+		if _, err := users.FindByEmail(ctx, "nobody@example.com"); !errors.Is(err, utils.ErrUserNotFound) {
+			t.Errorf("FindByEmail of an unknown address: err = %v, want ErrUserNotFound", err)
+		}
+	})
+}
+
+// TestUserAccountUpdates checks that the password, TOTP and verification
+// updates each save only their own columns.
+func TestUserAccountUpdates(t *testing.T) {
+	backends(t, func(t *testing.T, users UserRepository, _ PostRepository) {
+		ctx := context.Background()
+		anna := createUser(t, users, "anna", 0)
+
+		stale := *anna
+		stale.UserName = "stale"
+		stale.PasswordHash = "new-hash"
+		stale.UpdatedAt = testTime.Add(time.Hour)
+		if err := users.UpdatePassword(ctx, &stale); err != nil {
+			t.Fatal(err)
+		}
+
+		stale.TOTPEnabled = true
+		stale.TOTPSecret = "secret"
+		stale.TOTPLastStep = 42
+		stale.PasswordHash = "stale-hash"
+		if err := users.UpdateTOTP(ctx, &stale); err != nil {
+			t.Fatal(err)
+		}
+
+		verified := testTime.Add(2 * time.Hour)
+		stale.EmailVerifiedAt = &verified
+		stale.UserEmail = "stale@example.com"
+		if err := users.UpdateEmailVerified(ctx, &stale); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := users.FindByID(ctx, anna.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.PasswordHash != "new-hash" || !stored.TOTPEnabled || stored.TOTPSecret != "secret" || stored.TOTPLastStep != 42 {
+			t.Errorf("account fields not saved: %+v", stored)
+		}
+		if stored.EmailVerifiedAt == nil || !stored.EmailVerifiedAt.Equal(verified) {
+			t.Errorf("email_verified_at = %v, want %v", stored.EmailVerifiedAt, verified)
+		}
+		if stored.UserName != "anna" || stored.UserEmail != "anna@example.com" {
+			t.Errorf("profile overwritten: %q <%s>", stored.UserName, stored.UserEmail)
+		}
+
+		// Disabling clears the secret and step, which are zero values
+		stale.TOTPEnabled = false
+		stale.TOTPSecret = ""
+		stale.TOTPLastStep = 0
+		if err := users.UpdateTOTP(ctx, &stale); err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ := users.FindByID(ctx, anna.UserID); stored.TOTPEnabled || stored.TOTPSecret != "" || stored.TOTPLastStep != 0 {
+			t.Errorf("TOTP not cleared: %+v", stored)
+		}
+
+		missing := &models.User{UserID: 9999, PasswordHash: "x"}
+		if err := users.UpdatePassword(ctx, missing); !errors.Is(err, utils.ErrUserNotFound) {
+			t.Errorf("UpdatePassword of a missing user: err = %v, want ErrUserNotFound", err)
+		}
+	})
+}
+
+func TestUserRecordTOTPStep(t *testing.T) {
+	backends(t, func(t *testing.T, users UserRepository, _ PostRepository) {
+		ctx := context.Background()
+		anna := createUser(t, users, "anna", 0)
+
+		for _, tt := range []struct {
+			step int64
+			want bool
+		}{{100, true}, {100, false}, {99, false}, {101, true}} {
+			recorded, err := users.RecordTOTPStep(ctx, anna.UserID, tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recorded != tt.want {
+				t.Errorf("RecordTOTPStep(%d) = %v, want %v", tt.step, recorded, tt.want)
+			}
+		}
+		if stored, _ := users.FindByID(ctx, anna.UserID); stored.TOTPLastStep != 101 {
+			t.Errorf("totp_last_step = %d, want 101", stored.TOTPLastStep)
+		}
+		if recorded, err := users.RecordTOTPStep(ctx, 9999, 1); recorded || err != nil {
+			t.Errorf("RecordTOTPStep of a missing user = %v, %v", recorded, err)
+		}
+	})
+}
+
+var userFields = listquery.NewRegistry(
+	listquery.Field{Name: "name", Column: "user_name", Type: listquery.String,
+		Operators: []listquery.Operator{listquery.Equals, listquery.Contains}, Sortable: true},
+	listquery.Field{Name: "created_at", Column: "created_at", Type: listquery.Time,
+		Operators: []listquery.Operator{listquery.After, listquery.Between}, Sortable: true},
+	listquery.Field{Name: "email_verified_at", Column: "email_verified_at", Type: listquery.Time,
+		Operators: []listquery.Operator{listquery.IsNull}},
+)
+
+func TestUserList(t *testing.T) {
+	backends(t, func(t *testing.T, users UserRepository, _ PostRepository) {
+		ctx := context.Background()
+		for i, name := range []string{"carl", "anna", "annie", "bob"} {
+			createUser(t, users, name, i)
+		}
+		annie, _ := users.FindByID(ctx, 3)
+		verified := testTime
+		annie.EmailVerifiedAt = &verified
+		if err := users.Update(ctx, annie); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			query     string
+			sort      string
+			offset    int
+			limit     int
+			wantNames []string
+			wantTotal int64
+		}{
+			{wantNames: []string{"carl", "anna", "annie", "bob"}, wantTotal: 4},
+			{sort: "name", wantNames: []string{"anna", "annie", "bob", "carl"}, wantTotal: 4},
+			{sort: "created_at desc", limit: 2, wantNames: []string{"bob", "annie"}, wantTotal: 4},
+			{sort: "name", offset: 2, limit: 10, wantNames: []string{"bob", "carl"}, wantTotal: 4},
+			{query: "filter[name][contains]=ANN", sort: "name desc", wantNames: []string{"annie", "anna"}, wantTotal: 2},
+			{query: "filter[created_at][after]=2026-03-02T12:00:00Z", wantNames: []string{"annie", "bob"}, wantTotal: 2},
+			{query: "filter[created_at][between]=2026-03-02,2026-03-03", wantNames: []string{"anna", "annie"}, wantTotal: 2},
+			{query: "filter[email_verified_at][isnull]=false", wantNames: []string{"annie"}, wantTotal: 1},
+			{query: "filter[or][0][name][equals]=bob&filter[or][1][name][equals]=carl", limit: 1, wantNames: []string{"carl"}, wantTotal: 2},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query+" "+tt.sort, func(t *testing.T) {
+				query, _ := url.ParseQuery(tt.query)
+				filter, err := userFields.ParseFilter(query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts := UserListOptions{Filter: filter, Offset: tt.offset, Limit: tt.limit}
+				if tt.sort != "" {
+					name, direction, _ := strings.Cut(tt.sort, " ")
+					sort, err := userFields.Sort(name, direction)
+					if err != nil {
+						t.Fatal(err)
+					}
+					opts.Sort = []listquery.Sort{sort}
+				}
+				if opts.Limit == 0 {
+					opts.Limit = -1
+				}
+
+				list, total, err := users.List(ctx, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var names []string
+				for _, user := range list {
+					names = append(names, user.UserName)
+				}
+				if !slices.Equal(names, tt.wantNames) || total != tt.wantTotal {
+					t.Errorf("got %v of %d, want %v of %d", names, total, tt.wantNames, tt.wantTotal)
+				}
+			})
+		}
+	})
+}
+
+func TestPosts(t *testing.T) {
+	backends(t, func(t *testing.T, users UserRepository, posts PostRepository) {
+		ctx := context.Background()
+		anna := createUser(t, users, "anna", 0)
+		bob := createUser(t, users, "bob", 0)
+
+		if err := posts.Create(ctx, &models.Post{UserID: 9999, Body: "orphan"}); !errors.Is(err, utils.ErrUserNotFound) {
+			t.Errorf("Create for a missing author: err = %v, want ErrUserNotFound", err)
+		}
+
+		var created []*models.Post
+		for i, author := range []*models.User{anna, bob, anna} {
+			post := &models.Post{UserID: author.UserID, Body: author.UserName + " post", CreatedAt: testTime.Add(time.Duration(i) * time.Hour)}
+			if err := posts.Create(ctx, post); err != nil {
+				t.Fatal(err)
+			}
+			if post.Author == nil || post.Author.UserID != author.UserID {
+				t.Errorf("Create did not load the author: %+v", post.Author)
+			}
+			created = append(created, post)
+		}
+
+		found, err := posts.FindByID(ctx, created[1].PostID)
+		if err != nil || found.Author == nil || found.Author.UserName != "bob" {
+			t.Fatalf("FindByID = %+v, %v", found, err)
+		}
+		if _, err := posts.FindByID(ctx, 9999); !errors.Is(err, utils.ErrPostNotFound) {
+			t.Errorf("FindByID of a missing post: err = %v, want ErrPostNotFound", err)
+		}
+
+		list, total, err := posts.List(ctx, PostListOptions{AuthorID: anna.UserID, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 2 || len(list) != 2 || list[0].PostID != created[2].PostID || list[0].Author == nil {
+			t.Errorf("List by author = %+v (total %d), want anna's posts newest first", list, total)
+		}
+
+		found.Body = "edited"
+		found.UpdatedAt = testTime.Add(time.Hour)
+		if err := posts.UpdateBody(ctx, found); err != nil {
+			t.Fatal(err)
+		}
+		if edited, _ := posts.FindByID(ctx, found.PostID); edited.Body != "edited" {
+			t.Errorf("body after UpdateBody = %q", edited.Body)
+		}
+
+		if err := posts.Delete(ctx, found.PostID); err != nil {
+			t.Fatal(err)
+		}
+		if err := posts.Delete(ctx, found.PostID); !errors.Is(err, utils.ErrPostNotFound) {
+			t.Errorf("second Delete: err = %v, want ErrPostNotFound", err)
+		}
+	})
+}
+
+// TestDeleteUserCascadesToPosts relies on the foreign key in the SQLite
+// schema; the in-memory repositories do not cascade.
+func TestDeleteUserCascadesToPosts(t *testing.T) {
+	db := dbtest.Open(t)
+	users, posts := NewGormUserRepository(db), NewGormPostRepository(db)
+	ctx := context.Background()
+
+	anna := createUser(t, users, "anna", 0)
+	post := &models.Post{UserID: anna.UserID, Body: "hello"}
+	if err := posts.Create(ctx, post); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, anna.UserID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := posts.FindByID(ctx, post.PostID); !errors.Is(err, utils.ErrPostNotFound) {
+		t.Errorf("post survived its author: err = %v", err)
+	}
+}
+
+// useZone makes name the local time zone for one test, as on a host not
+// running in UTC.
+func useZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	previous := time.Local
+	time.Local = zone
+	t.Cleanup(func() { time.Local = previous })
+	return zone
+}
+
+// TestTimesStoredInUTC checks that timestamps reach SQLite in UTC whether
+// GORM stamps them or the caller passes local times, since SQLite compares
+// them as text.
+func TestTimesStoredInUTC(t *testing.T) {
+	zone := useZone(t, "Asia/Karachi")
+	db := dbtest.Open(t)
+	users, posts := NewGormUserRepository(db), NewGormPostRepository(db)
+	ctx := context.Background()
+
+	stamped := &models.User{UserName: "anna", UserEmail: "anna@example.com", Role: models.RoleUser}
+	if err := users.Create(ctx, stamped); err != nil {
+		t.Fatal(err)
+	}
+	local := time.Date(2026, 1, 2, 3, 0, 0, 0, zone)
+	given := &models.User{UserName: "bob", UserEmail: "bob@example.com", Role: models.RoleUser,
+		CreatedAt: local, UpdatedAt: local, EmailVerifiedAt: &local}
+	if err := users.Create(ctx, given); err != nil {
+		t.Fatal(err)
+	}
+	if err := posts.Create(ctx, &models.Post{UserID: given.UserID, Body: "hello", CreatedAt: local}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, column := range []string{"users.created_at", "users.updated_at", "users.email_verified_at", "posts.created_at", "posts.updated_at"} {
+		table, name, _ := strings.Cut(column, ".")
+		var stored []string
+		if err := db.Table(table).Where(name+" IS NOT NULL").Pluck("CAST("+name+" AS TEXT)", &stored).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, text := range stored {
+			if !strings.HasSuffix(text, "+00:00") {
+				t.Errorf("%s stored as %q, want UTC", column, text)
+			}
+		}
+	}
+}
+
+var createdFields = listquery.NewRegistry(
+	listquery.Field{Name: "created_at", Column: "created_at", Type: listquery.Time,
+		Operators: []listquery.Operator{listquery.Equals, listquery.Before, listquery.After, listquery.Between}},
+)
+
+// TestTimeFiltersIgnoreLocalZone checks that time filters pick the same rows
+// from SQLite and from memory when rows are created in the host's zone,
+// including the one set by TZ, and on either side of UTC.
+func TestTimeFiltersIgnoreLocalZone(t *testing.T) {
+	for _, zone := range []string{"Local", "Asia/Karachi", "America/Los_Angeles"} {
+		t.Run(zone, func(t *testing.T) {
+			useZone(t, zone)
+			queries := []string{
+				"filter[created_at][equals]=2026-01-01",
+				"filter[created_at][equals]=2026-01-02",
+				"filter[created_at][after]=2026-01-01T23:00:00Z",
+				"filter[created_at][before]=2026-01-02T00:00:00%2B05:00",
+				"filter[created_at][between]=2026-01-01,2026-01-02",
+			}
+			results := map[string]map[string][]string{}
+			backends(t, func(t *testing.T, users UserRepository, _ PostRepository) {
+				ctx := context.Background()
+				for i, created := range []time.Time{
+					time.Date(2026, 1, 1, 0, 30, 0, 0, time.Local),
+					time.Date(2026, 1, 1, 23, 30, 0, 0, time.Local),
+					time.Date(2026, 1, 2, 3, 0, 0, 0, time.Local),
+					time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local),
+				} {
+					user := &models.User{UserName: fmt.Sprintf("user%d", i), UserEmail: fmt.Sprintf("user%d@example.com", i),
+						Role: models.RoleUser, CreatedAt: created, UpdatedAt: created}
+					if err := users.Create(ctx, user); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				results[t.Name()] = map[string][]string{}
+				for _, raw := range queries {
+					query, _ := url.ParseQuery(raw)
+					filter, err := createdFields.ParseFilter(query)
+					if err != nil {
+						t.Fatal(err)
+					}
+					list, _, err := users.List(ctx, UserListOptions{Filter: filter, Limit: -1})
+					if err != nil {
+						t.Fatal(err)
+					}
+					var names []string
+					for _, user := range list {
+						names = append(names, user.UserName)
+					}
+					results[t.Name()][raw] = names
+				}
+			})
+
+			sqlite, memory := results[t.Name()+"/sqlite"], results[t.Name()+"/memory"]
+			for _, raw := range queries {
+				if !slices.Equal(sqlite[raw], memory[raw]) {
+					t.Errorf("%s: sqlite returned %v, memory %v", raw, sqlite[raw], memory[raw])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Package routes wires handlers and their middleware chains into the HTTP
+// router.
+package routes
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"main/internal/config"
+	"main/internal/handlers"
+	"main/internal/middleware"
+	"main/internal/utils"
+	"main/pkg/metrics"
+	"main/pkg/tracing"
+)
+
+// APIPrefix is the base path of the current API version.
+const APIPrefix = "/api/v1"
+
+// Handlers holds the handler sets built around injected repositories.
+type Handlers struct {
+	Users *handlers.UserHandlers
+	Posts *handlers.PostHandlers
+	Auth  *handlers.AuthHandlers
+}
+
+// New builds the router serving the versioned API, the deprecated
+// pre-versioning paths (when enabled), operational endpoints and the static
+// frontend.
+func New(cfg config.Config, h Handlers) *mux.Router {
+	router := mux.NewRouter()
+	// mux only reports a method mismatch when the mismatching route is the
+	// last candidate it tried, so both cases go through unmatched
+	router.NotFoundHandler = unmatched(router)
+	router.MethodNotAllowedHandler = unmatched(router)
+	router.Use(recordRoute)
+
+	// Health probes and metrics, deliberately outside authentication and
+	// rate limiting
+	router.HandleFunc("/healthz", handlers.HealthzHandler).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/readyz", handlers.ReadyzHandler).Methods(http.MethodGet, http.MethodHead)
+	if cfg.Metrics.Enabled {
+		router.Handle(cfg.Metrics.Path, metrics.Handler()).Methods(http.MethodGet)
+	}
+
+	registerAPI(router, router.PathPrefix(APIPrefix).Subrouter(), h)
+
+	if cfg.Server.LegacyRoutes {
+		registerLegacy(router, h)
+	}
+
+	// Serve the static frontend for every other GET outside the API. Only
+	// existing files match, so other paths get a JSON 404 and a POST to a
+	// missing file is not answered with 405.
+	static := http.Dir(cfg.Server.StaticDir)
+	router.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return !strings.HasPrefix(r.URL.Path, "/api/") && staticFileExists(static, r.URL.Path)
+	}).
+		PathPrefix("/").
+		Methods(http.MethodGet, http.MethodHead).
+		Handler(http.FileServer(static))
+
+	return router
+}
+
+func registerAPI(router, api *mux.Router, h Handlers) {
+	// Preflights are answered with the methods the requested path supports
+	api.Use(adapt(middleware.CORSForMethods(func(r *http.Request) []string {
+		return allowedMethods(router, r)
+	})))
+	// Match preflights for every API path; CORS writes the response. A
+	// method matcher would make unknown paths look like 405s.
+	api.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return r.Method == http.MethodOptions
+	}).HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	// Each chain is a matcher-less subrouter sharing the API prefix
+	public := api.NewRoute().Subrouter()
+	public.Use(adapt(middleware.RateLimiter))
+
+	authLimited := api.NewRoute().Subrouter()
+	authLimited.Use(routeLimit("auth"))
+
+	authenticated := api.NewRoute().Subrouter()
+	authenticated.Use(adapt(middleware.Authenticate), adapt(middleware.RateLimiter))
+
+	authenticatedAuthLimited := api.NewRoute().Subrouter()
+	authenticatedAuthLimited.Use(adapt(middleware.Authenticate), routeLimit("auth"))
+
+	emailLimited := api.NewRoute().Subrouter()
+	emailLimited.Use(routeLimit("email"))
+
+	// Posts
+	public.HandleFunc("/posts", h.Posts.List).Methods(http.MethodGet)
+	public.HandleFunc("/posts/{id:[0-9]+}", h.Posts.Get).Methods(http.MethodGet)
+	authenticated.HandleFunc("/posts", middleware.RequireVerifiedEmail(h.Posts.Create)).Methods(http.MethodPost)
+	authenticated.HandleFunc("/posts/{id:[0-9]+}", h.Posts.Update).Methods(http.MethodPatch, http.MethodPut)
+	authenticated.HandleFunc("/posts/{id:[0-9]+}", h.Posts.Delete).Methods(http.MethodDelete)
+
+	// Users
+	authenticated.HandleFunc("/users", h.Users.List).Methods(http.MethodGet)
+	authenticated.HandleFunc("/users", middleware.Authorize(middleware.PermManageUsers, h.Users.Create)).Methods(http.MethodPost)
+	authenticated.HandleFunc("/users/{id:[0-9]+}", h.Users.Get).Methods(http.MethodGet)
+	authenticated.HandleFunc("/users/{id:[0-9]+}", h.Users.Update).Methods(http.MethodPatch)
+	authenticated.HandleFunc("/users/{id:[0-9]+}", h.Users.Delete).Methods(http.MethodDelete)
+	authenticated.HandleFunc("/users/{id:[0-9]+}/role", middleware.Authorize(middleware.PermManageRoles, h.Users.UpdateRole)).Methods(http.MethodPut)
+
+	// Authentication
+	authLimited.HandleFunc("/auth/signup", h.Auth.Signup).Methods(http.MethodPost)
+	authLimited.HandleFunc("/auth/login", h.Auth.Login).Methods(http.MethodPost)
+	authLimited.HandleFunc("/auth/login/2fa", h.Auth.LoginMFA).Methods(http.MethodPost)
+	authLimited.HandleFunc("/auth/refresh", h.Auth.Refresh).Methods(http.MethodPost)
+	authLimited.HandleFunc("/auth/verify", h.Auth.VerifyEmail).Methods(http.MethodGet, http.MethodPost)
+	authLimited.HandleFunc("/auth/password/forgot", h.Auth.ForgotPassword).Methods(http.MethodPost)
+	authLimited.HandleFunc("/auth/password/reset", h.Auth.ResetPassword).Methods(http.MethodPost)
+	authenticated.HandleFunc("/auth/logout", h.Auth.Logout).Methods(http.MethodPost)
+	authenticated.HandleFunc("/auth/sessions", h.Auth.Sessions).Methods(http.MethodGet)
+	authenticated.HandleFunc("/auth/sessions/{id:[0-9]+}", h.Auth.RevokeSession).Methods(http.MethodDelete)
+	authenticated.HandleFunc("/auth/2fa/enroll", h.Auth.EnrollTOTP).Methods(http.MethodPost)
+	authenticatedAuthLimited.HandleFunc("/auth/2fa/confirm", h.Auth.ConfirmTOTP).Methods(http.MethodPost)
+	authenticatedAuthLimited.HandleFunc("/auth/2fa/disable", h.Auth.DisableTOTP).Methods(http.MethodPost)
+	authenticatedAuthLimited.HandleFunc("/auth/verify/resend", h.Auth.ResendVerification).Methods(http.MethodPost)
+
+	// Contact form
+	emailLimited.HandleFunc("/contact", handlers.SendEmailHandler).Methods(http.MethodPost)
+}
+
+// registerLegacy keeps the paths used before the API was versioned working
+// during the transition. They behave as before, accept any method, and
+// point clients at their replacement through Deprecation and Link headers.
+func registerLegacy(router *mux.Router, h Handlers) {
+	legacy := func(path, successor string, h http.HandlerFunc) {
+		router.Handle(path, deprecated(successor, h))
+	}
+
+	legacy("/post", APIPrefix+"/posts", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(middleware.RequireVerifiedEmail(h.Posts.LegacyCreate)))))
+	legacy("/get", "", middleware.CORS(middleware.RateLimiter(handlers.GetHandler)))
+	legacy("/users", APIPrefix+"/users", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Users.Collection))))
+	legacy("/user/create", APIPrefix+"/users", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(middleware.Authorize(middleware.PermManageUsers, h.Users.Create)))))
+	legacy("/user/update", APIPrefix+"/users/{id}", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Users.Update))))
+	legacy("/user/delete", APIPrefix+"/users/{id}", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Users.Delete))))
+	legacy("/user/get", APIPrefix+"/users/{id}", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Users.Get))))
+	legacy("/user/role", APIPrefix+"/users/{id}/role", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(middleware.Authorize(middleware.PermManageRoles, h.Users.UpdateRole)))))
+	legacy("/api/posts", APIPrefix+"/posts", middleware.RateLimiter(middleware.CORS(h.Posts.Collection)))
+	legacy("/api/posts/{id}", APIPrefix+"/posts/{id}", middleware.RateLimiter(middleware.CORS(h.Posts.Item)))
+	legacy("/api/auth/signup", APIPrefix+"/auth/signup", middleware.RouteRateLimiter("auth", middleware.CORS(h.Auth.Signup)))
+	legacy("/api/auth/login", APIPrefix+"/auth/login", middleware.RouteRateLimiter("auth", middleware.CORS(h.Auth.Login)))
+	legacy("/api/auth/login/2fa", APIPrefix+"/auth/login/2fa", middleware.RouteRateLimiter("auth", middleware.CORS(h.Auth.LoginMFA)))
+	legacy("/api/auth/2fa/enroll", APIPrefix+"/auth/2fa/enroll", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Auth.EnrollTOTP))))
+	legacy("/api/auth/2fa/confirm", APIPrefix+"/auth/2fa/confirm", middleware.CORS(middleware.Authenticate(middleware.RouteRateLimiter("auth", h.Auth.ConfirmTOTP))))
+	legacy("/api/auth/2fa/disable", APIPrefix+"/auth/2fa/disable", middleware.CORS(middleware.Authenticate(middleware.RouteRateLimiter("auth", h.Auth.DisableTOTP))))
+	legacy("/api/auth/refresh", APIPrefix+"/auth/refresh", middleware.RouteRateLimiter("auth", middleware.CORS(h.Auth.Refresh)))
+	legacy("/api/auth/logout", APIPrefix+"/auth/logout", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Auth.Logout))))
+	legacy("/api/auth/sessions", APIPrefix+"/auth/sessions", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Auth.Sessions))))
+	legacy("/api/auth/sessions/{id}", APIPrefix+"/auth/sessions/{id}", middleware.CORS(middleware.Authenticate(middleware.RateLimiter(h.Auth.RevokeSession))))
+	legacy("/api/auth/verify", APIPrefix+"/auth/verify", middleware.RouteRateLimiter("auth", middleware.CORS(h.Auth.VerifyEmail)))
+	legacy("/api/auth/verify/resend", APIPrefix+"/auth/verify/resend", middleware.CORS(middleware.Authenticate(middleware.RouteRateLimiter("auth", h.Auth.ResendVerification))))
+	legacy("/api/auth/password/forgot", APIPrefix+"/auth/password/forgot", middleware.RouteRateLimiter("auth", middleware.CORS(h.Auth.ForgotPassword)))
+	legacy("/api/auth/password/reset", APIPrefix+"/auth/password/reset", middleware.RouteRateLimiter("auth", middleware.CORS(h.Auth.ResetPassword)))
+	legacy("/send-email", APIPrefix+"/contact", middleware.RouteRateLimiter("email", middleware.CORS(handlers.SendEmailHandler)))
+}
+
+// deprecated marks responses from a legacy path as deprecated and, when
+// there is one, links the path that replaces it.
+func deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		if successor != "" {
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		utils.LoggerFromContext(r.Context()).WithField("successor", successor).Debug("Deprecated route used")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// staticFileExists reports whether path names a file or directory in dir.
+func staticFileExists(dir http.Dir, path string) bool {
+	file, err := dir.Open(path)
+	if err != nil {
+		return false
+	}
+	file.Close()
+	return true
+}
+
+// adapt turns the repo's HandlerFunc middleware into mux middleware.
+func adapt(mw func(http.HandlerFunc) http.HandlerFunc) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return mw(next.ServeHTTP)
+	}
+}
+
+func routeLimit(route string) mux.MiddlewareFunc {
+	return adapt(func(next http.HandlerFunc) http.HandlerFunc {
+		return middleware.RouteRateLimiter(route, next)
+	})
+}
+
+// recordRoute labels metrics and the server span with the matched route
+// template rather than the raw path.
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				utils.RecordRoute(r.Context(), template)
+				tracing.RecordRoute(r.Context(), r.Method, template)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unmatched answers 405 with an Allow header when a route serves the path
+// with other methods, and 404 otherwise.
+func unmatched(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods := allowedMethods(router, r)
+		if len(methods) == 0 {
+			utils.SendErrorResponse(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		utils.SendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+}
+
+// allowedMethods lists, sorted, the methods some route serves r's path with.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	allowed := map[string]bool{}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if route.Match(probe, &mux.RouteMatch{}) {
+				allowed[method] = true
+			}
+		}
+		return nil
+	})
+
+	methods := make([]string, 0, len(allowed))
+	for method := range allowed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
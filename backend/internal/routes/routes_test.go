@@ -0,0 +1,175 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"main/internal/config"
+	"main/internal/handlers"
+	"main/internal/middleware"
+	"main/internal/repository"
+	"main/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newRouter builds the router on in-memory repositories, serving a
+// frontend that only has index.html.
+func newRouter(t *testing.T, legacyRoutes bool) *mux.Router {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Server.StaticDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(cfg.Server.StaticDir, "index.html"), []byte("<!doctype html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Server.LegacyRoutes = legacyRoutes
+	users := repository.NewMemoryUserRepository()
+	return New(cfg, Handlers{
+		Users: handlers.NewUserHandlers(users),
+		Posts: handlers.NewPostHandlers(repository.NewMemoryPostRepository(users)),
+		Auth:  handlers.NewAuthHandlers(users),
+	})
+}
+
+func request(router http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	router := newRouter(t, true)
+	tests := []struct {
+		method, target string
+		wantAllow      string
+	}{
+		{http.MethodDelete, "/api/v1/posts", "GET, POST"},
+		{http.MethodPost, "/api/v1/posts/7", "DELETE, GET, PATCH, PUT"},
+		{http.MethodPost, "/api/v1/users/7", "DELETE, GET, PATCH"},
+		{http.MethodGet, "/api/v1/users/7/role", "PUT"},
+		{http.MethodGet, "/api/v1/auth/login", "POST"},
+		{http.MethodPut, "/api/v1/auth/verify", "GET, POST"},
+		{http.MethodPost, "/healthz", "GET, HEAD"},
+		{http.MethodDelete, "/readyz", "GET, HEAD"},
+		{http.MethodPost, "/metrics", "GET"},
+		{http.MethodPost, "/index.html", "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			w := request(router, tt.method, tt.target)
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want 405", w.Code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	router := newRouter(t, false)
+	for _, target := range []string{
+		"/api/v1/nope",
+		"/api/v1/posts/abc",
+		"/api/v1/posts/7/comments",
+		"/api/v2/posts",
+		"/api/posts/7/comments",
+		"/missing.html",
+		"/users",
+	} {
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			w := request(router, method, target)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s %s: status = %d, want 404", method, target, w.Code)
+			}
+			if allow := w.Header().Get("Allow"); allow != "" {
+				t.Errorf("%s %s: Allow = %q on a 404", method, target, allow)
+			}
+		}
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	router := newRouter(t, true)
+	if w := request(router, http.MethodGet, "/"); w.Code != http.StatusOK {
+		t.Errorf("GET /: status = %d, want the frontend", w.Code)
+	}
+	if w := request(router, http.MethodGet, "/api/v1/posts"); w.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/posts: status = %d", w.Code)
+	}
+	if w := request(router, http.MethodGet, "/api/v1/posts/7"); w.Code != http.StatusNotFound {
+		t.Errorf("GET of a missing post: status = %d, want 404 from the handler", w.Code)
+	}
+	if w := request(router, http.MethodGet, "/api/v1/users"); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/users without a token: status = %d, want 401", w.Code)
+	}
+	if w := request(router, http.MethodGet, "/api/v1/posts"); w.Header().Get("Deprecation") != "" {
+		t.Error("versioned route marked deprecated")
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	router := newRouter(t, true)
+	tests := []struct {
+		method, target string
+		wantStatus     int
+		wantLink       string
+	}{
+		{http.MethodGet, "/api/posts", http.StatusOK, `</api/v1/posts>; rel="successor-version"`},
+		{http.MethodGet, "/api/posts/7", http.StatusNotFound, `</api/v1/posts/{id}>; rel="successor-version"`},
+		{http.MethodGet, "/users", http.StatusUnauthorized, `</api/v1/users>; rel="successor-version"`},
+		{http.MethodPost, "/api/auth/login", http.StatusBadRequest, `</api/v1/auth/login>; rel="successor-version"`},
+		{http.MethodGet, "/get?message=hello", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			w := request(router, tt.method, tt.target)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Deprecation"); got != "true" {
+				t.Errorf("Deprecation = %q, want true", got)
+			}
+			if got := w.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link = %q, want %q", got, tt.wantLink)
+			}
+		})
+	}
+}
+
+func TestLegacyRoutesDisabled(t *testing.T) {
+	router := newRouter(t, false)
+	for _, target := range []string{"/api/posts", "/users", "/send-email"} {
+		if w := request(router, http.MethodPost, target); w.Code != http.StatusNotFound {
+			t.Errorf("POST %s: status = %d, want 404", target, w.Code)
+		}
+	}
+}
+
+func TestPreflightListsRouteMethods(t *testing.T) {
+	router := newRouter(t, false)
+	middleware.SetCORSOptions(middleware.CORSOptions(config.Default().CORS))
+	t.Cleanup(func() { middleware.SetCORSOptions(middleware.CORSOptions{}) })
+
+	r := httptest.NewRequest(http.MethodOptions, "/api/v1/posts/7", nil)
+	r.Header.Set("Origin", "http://localhost:3000")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE, GET, PATCH, PUT" {
+		t.Errorf("Access-Control-Allow-Methods = %q", got)
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.168.1.7 ", "", "fd00::/8", "::ffff:172.16.0.0/108"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.7/32", "fd00::/8", "172.16.0.0/12"}
+	if len(prefixes) != len(want) {
+		t.Fatalf("got %v, want %v", prefixes, want)
+	}
+	for i, prefix := range prefixes {
+		if prefix.String() != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, prefix, want[i])
+		}
+	}
+
+	for _, entry := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0"} {
+		if _, err := ParseTrustedProxies([]string{entry}); err == nil {
+			t.Errorf("%q accepted", entry)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc       string
+		trusted    bool
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"no proxies configured", false, "10.0.0.1:4000", []string{"203.0.113.9"}, "203.0.113.8", "10.0.0.1"},
+		{"untrusted peer", true, "198.51.100.1:4000", []string{"203.0.113.9"}, "203.0.113.8", "198.51.100.1"},
+		{"trusted peer without headers", true, "10.0.0.1:4000", nil, "", "10.0.0.1"},
+		{"single hop", true, "10.0.0.1:4000", []string{"203.0.113.9"}, "", "203.0.113.9"},
+		{"spoofed leading entry", true, "10.0.0.1:4000", []string{"1.2.3.4, 203.0.113.9"}, "", "203.0.113.9"},
+		{"chain of trusted proxies", true, "10.0.0.1:4000", []string{"203.0.113.9, 10.1.1.1", "10.2.2.2"}, "", "203.0.113.9"},
+		{"only trusted hops", true, "10.0.0.1:4000", []string{"10.1.1.1, 10.2.2.2"}, "", "10.1.1.1"},
+		{"garbage before the trusted hop", true, "10.0.0.1:4000", []string{"not-an-ip, 10.2.2.2"}, "", "10.2.2.2"},
+		{"forwarded beats real ip", true, "10.0.0.1:4000", []string{"203.0.113.9"}, "203.0.113.8", "203.0.113.9"},
+		{"real ip", true, "10.0.0.1:4000", nil, " 203.0.113.8 ", "203.0.113.8"},
+		{"invalid real ip", true, "10.0.0.1:4000", nil, "unknown", "10.0.0.1"},
+		{"mapped IPv4 peer", true, "[::ffff:10.0.0.1]:4000", []string{"203.0.113.9"}, "", "203.0.113.9"},
+		{"IPv6 client", true, "10.0.0.1:4000", []string{"2001:db8::1"}, "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if tt.trusted {
+				SetTrustedProxies(proxies)
+			} else {
+				SetTrustedProxies(nil)
+			}
+			t.Cleanup(func() { SetTrustedProxies(nil) })
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
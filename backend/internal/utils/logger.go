@@ -1,16 +1,18 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
-	"sort"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"main/pkg/metrics"
 )
 
 // CustomFormatter extends logrus.JSONFormatter
@@ -18,8 +20,14 @@ type CustomFormatter struct {
 	logrus.JSONFormatter
 }
 
-// Global logger instance
-var Log *logrus.Logger
+// Global logger instance. It logs to stderr until InitLogger configures it.
+var Log = logrus.New()
+
+// logFileName is the active log file inside LogConfig.LogPath. Rotated
+// segments are stored next to it as app-<timestamp>.log.gz.
+const logFileName = "app.log"
+
+var logWriter *RotatingWriter
 
 // LogConfig holds configuration for the logger
 type LogConfig struct {
@@ -87,14 +95,14 @@ func InitLogger(config LogConfig) error {
 	}
 
 	// Setup log file
-	currentTime := time.Now().Format("2006-01-02")
-	logFileName := fmt.Sprintf("app-%s.log", currentTime)
-	logFilePath := filepath.Join(config.LogPath, logFileName)
-
-	logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	logFile, err := NewRotatingWriter(filepath.Join(config.LogPath, logFileName), config)
 	if err != nil {
-		return fmt.Errorf("failed to open log file: %v", err)
+		return err
+	}
+	if logWriter != nil {
+		logWriter.Close()
 	}
+	logWriter = logFile
 
 	// Configure output writers
 	var writers []io.Writer
@@ -114,6 +122,16 @@ func InitLogger(config LogConfig) error {
 	return nil
 }
 
+// CloseLogger flushes and closes the log file opened by InitLogger.
+func CloseLogger() error {
+	if logWriter == nil {
+		return nil
+	}
+	err := logWriter.Close()
+	logWriter = nil
+	return err
+}
+
 // LoggerMiddleware creates a middleware that logs HTTP requests
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -131,8 +149,9 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 		// Calculate duration
 		duration := time.Since(startTime)
 
-		// Log the request details
-		Log.WithFields(logrus.Fields{
+		// Log the request details, tagged with the request ID when
+		// RequestIDMiddleware runs first
+		LoggerFromContext(r.Context()).WithFields(logrus.Fields{
 			"method":     r.Method,
 			"path":       r.URL.Path,
 			"status":     rw.statusCode,
@@ -143,6 +162,41 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+type routeContextKey struct{}
+
+// RecordRoute reports the route template matched for the request in ctx to
+// MetricsMiddleware. The router calls it once a route has matched.
+func RecordRoute(ctx context.Context, template string) {
+	if route, ok := ctx.Value(routeContextKey{}).(*string); ok {
+		*route = template
+	}
+}
+
+// MetricsMiddleware records request counts and latencies by route template
+// and status. Routes are labelled with the template reported through
+// RecordRoute, not the raw path, to keep label cardinality bounded.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		rw := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+
+		route := "unmatched"
+		ctx := context.WithValue(r.Context(), routeContextKey{}, &route)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		status := strconv.Itoa(rw.statusCode)
+
+		metrics.HTTPRequests.WithLabelValues(r.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(startTime).Seconds())
+	})
+}
+
 // Custom response writer to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -198,90 +252,3 @@ func LogResponse(statusCode int, duration time.Duration, fields logrus.Fields) {
 
 	Log.WithFields(fields).Info("Response sent")
 }
-
-// RotateLogs checks if log rotation is needed and performs rotation if necessary
-func RotateLogs(config LogConfig) error {
-	if !config.EnableRotation {
-		return nil
-	}
-
-	logDir := config.LogPath
-	files, err := os.ReadDir(logDir)
-	if err != nil {
-		return fmt.Errorf("failed to read log directory: %v", err)
-	}
-
-	var totalSize int64
-	var oldestFile os.FileInfo
-	oldestTime := time.Now()
-
-	for _, file := range files {
-		if filepath.Ext(file.Name()) != ".log" {
-			continue
-		}
-
-		info, err := file.Info()
-		if err != nil {
-			continue
-		}
-
-		totalSize += info.Size()
-
-		if info.ModTime().Before(oldestTime) {
-			oldestTime = info.ModTime()
-			oldestFile = info
-		}
-	}
-
-	// Check if total size exceeds max size
-	if totalSize > int64(config.MaxSize)*1024*1024 && oldestFile != nil {
-		oldPath := filepath.Join(logDir, oldestFile.Name())
-		backupPath := filepath.Join(logDir, fmt.Sprintf("%s.%s.backup",
-			oldestFile.Name(), time.Now().Format("20060102150405")))
-
-		if err := os.Rename(oldPath, backupPath); err != nil {
-			return fmt.Errorf("failed to rotate log file: %v", err)
-		}
-
-		// Remove old backups if exceeding MaxBackups
-		if config.MaxBackups > 0 {
-			removeOldBackups(logDir, config.MaxBackups)
-		}
-	}
-
-	return nil
-}
-
-// removeOldBackups removes old backup files exceeding the maximum count
-func removeOldBackups(logDir string, maxBackups int) error {
-	files, err := os.ReadDir(logDir)
-	if err != nil {
-		return err
-	}
-
-	var backups []os.FileInfo
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".backup" {
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
-			backups = append(backups, info)
-		}
-	}
-
-	// Sort backups by modification time (oldest first)
-	sort.Slice(backups, func(i, j int) bool {
-		return backups[i].ModTime().Before(backups[j].ModTime())
-	})
-
-	// Remove excess backups
-	for i := 0; i < len(backups)-maxBackups; i++ {
-		path := filepath.Join(logDir, backups[i].Name())
-		if err := os.Remove(path); err != nil {
-			Log.WithError(err).Warn("Failed to remove old backup")
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs accepted from clients or upstream proxies.
+const maxRequestIDLength = 128
+
+type requestContextKey string
+
+const (
+	requestIDContextKey requestContextKey = "request_id"
+	loggerContextKey    requestContextKey = "logger"
+)
+
+// RequestIDMiddleware tags each request with an ID, taken from an incoming
+// X-Request-ID header when it looks sane and generated otherwise. The ID is
+// echoed in the response and attached to a request-scoped logger stored in
+// the context.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		fields := logrus.Fields{
+			"request_id": id,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+		}
+		// Correlate log lines with the trace when tracing is enabled
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+			fields["trace_id"] = spanContext.TraceID().String()
+			fields["span_id"] = spanContext.SpanID().String()
+		}
+		logger := Log.WithFields(fields)
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+		ctx = ContextWithLogger(ctx, logger)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the ID assigned by RequestIDMiddleware, or ""
+// outside of a request.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// ContextWithLogger stores logger as the request-scoped logger, e.g. after
+// adding fields that later log lines should carry.
+func ContextWithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// LoggerFromContext returns the request-scoped logger, falling back to the
+// global logger outside of a request.
+func LoggerFromContext(ctx context.Context) *logrus.Entry {
+	if logger, ok := ctx.Value(loggerContextKey).(*logrus.Entry); ok {
+		return logger
+	}
+	return logrus.NewEntry(Log)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("utils: crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// isValidRequestID accepts short IDs made of characters that are safe to
+// echo in headers and log lines.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,363 @@
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	dayFormat          = "2006-01-02"
+	backupTimeFormat   = "2006-01-02T15-04-05.000"
+	compressedLogExt   = ".gz"
+	compressionTempExt = ".tmp"
+)
+
+// RotatingWriter is an io.Writer backed by a log file that is rotated at
+// midnight and whenever it would grow past MaxSize. Rotated segments are
+// renamed with a timestamp, compressed with gzip and pruned according to
+// MaxBackups and MaxAge. It also reopens its file on SIGHUP so an external
+// logrotate can move it aside.
+type RotatingWriter struct {
+	path       string
+	rotate     bool
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	// now is the clock deciding the day and naming backups, replaced in tests.
+	now func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	day    string
+	millMu sync.Mutex
+	millWG sync.WaitGroup
+
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+// NewRotatingWriter opens (or creates) path for appending. Rotation only
+// happens when config.EnableRotation is set; reopening on SIGHUP always does.
+func NewRotatingWriter(path string, config LogConfig) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		path:       path,
+		rotate:     config.EnableRotation,
+		maxSize:    int64(config.MaxSize) * 1024 * 1024,
+		maxBackups: config.MaxBackups,
+		maxAge:     time.Duration(config.MaxAge) * 24 * time.Hour,
+		now:        time.Now,
+		signals:    make(chan os.Signal, 1),
+		done:       make(chan struct{}),
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	// Segments left over from an earlier run may still need compressing or
+	// pruning.
+	w.mill()
+
+	signal.Notify(w.signals, syscall.SIGHUP)
+	go w.watchSignals()
+
+	return w, nil
+}
+
+// Write appends p to the current file, rotating first if the day has changed
+// or p would take the file past the size limit.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.rotate && w.needsRotation(int64(len(p))) {
+		if err := w.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside and starts a new one.
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotateLocked()
+}
+
+// Reopen closes and reopens the file at the configured path. It is used after
+// an external tool has renamed the file out from under the writer.
+func (w *RotatingWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %v", err)
+		}
+		w.file = nil
+	}
+	return w.open()
+}
+
+// Close stops watching for SIGHUP, waits for pending compression and closes
+// the file.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	if w.file == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	signal.Stop(w.signals)
+	close(w.done)
+
+	err := w.file.Close()
+	w.file = nil
+	w.mu.Unlock()
+
+	w.millWG.Wait()
+	return err
+}
+
+func (w *RotatingWriter) watchSignals() {
+	for {
+		select {
+		case <-w.signals:
+			if err := w.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to reopen log file: %v\n", err)
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *RotatingWriter) needsRotation(incoming int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.now().Format(dayFormat) != w.day {
+		return true
+	}
+	return w.maxSize > 0 && w.size+incoming > w.maxSize
+}
+
+func (w *RotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
+
+	w.file = file
+	w.size = info.Size()
+	// A file carried over from an earlier day is rotated on the next write.
+	w.day = w.now().Format(dayFormat)
+	if w.size > 0 {
+		w.day = info.ModTime().Format(dayFormat)
+	}
+	return nil
+}
+
+func (w *RotatingWriter) rotateLocked() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %v", err)
+		}
+		w.file = nil
+	}
+
+	if _, err := os.Stat(w.path); err == nil {
+		if err := os.Rename(w.path, w.backupPath(w.now())); err != nil {
+			// Keep logging to the old file rather than losing entries
+			if openErr := w.open(); openErr != nil {
+				return openErr
+			}
+			return fmt.Errorf("failed to rotate log file: %v", err)
+		}
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.mill()
+	return nil
+}
+
+// backupPath names a rotated segment after the active file and the time it
+// was rotated, e.g. app-2006-01-02T15-04-05.000.log.
+func (w *RotatingWriter) backupPath(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	return filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+}
+
+func (w *RotatingWriter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.path)
+	name := filepath.Base(w.path)
+	ext = filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+// mill compresses and prunes rotated segments in the background.
+func (w *RotatingWriter) mill() {
+	w.millWG.Add(1)
+	go func() {
+		defer w.millWG.Done()
+		w.millMu.Lock()
+		defer w.millMu.Unlock()
+
+		if err := w.compressBackups(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to compress log backups: %v\n", err)
+		}
+		if err := w.removeOldBackups(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to remove old log backups: %v\n", err)
+		}
+	}()
+}
+
+type logBackup struct {
+	path    string
+	modTime time.Time
+}
+
+// backups lists rotated segments, newest first.
+func (w *RotatingWriter) backups() ([]logBackup, error) {
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []logBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressedLogExt)
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(stamp, ext)); err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{path: filepath.Join(dir, name), modTime: info.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	return backups, nil
+}
+
+func (w *RotatingWriter) compressBackups() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		if strings.HasSuffix(backup.path, compressedLogExt) {
+			continue
+		}
+		if err := compressFile(backup.path, backup.modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeOldBackups deletes segments beyond MaxBackups or older than MaxAge.
+// A zero value disables the respective limit.
+func (w *RotatingWriter) removeOldBackups() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	cutoff := w.now().Add(-w.maxAge)
+	for i, backup := range backups {
+		tooMany := w.maxBackups > 0 && i >= w.maxBackups
+		tooOld := w.maxAge > 0 && backup.modTime.Before(cutoff)
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressFile gzips path next to itself and removes the original. The
+// archive keeps the original modification time so MaxAge still applies to
+// when the segment was written, not when it was compressed.
+func compressFile(path string, modTime time.Time) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst := path + compressedLogExt
+	tmp := dst + compressionTempExt
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	gz.Name = filepath.Base(path)
+	gz.ModTime = modTime
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress %s: %v", path, err)
+	}
+
+	if err := os.Chtimes(tmp, modTime, modTime); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,293 @@
+package utils
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testClock is a settable clock safe to read from the compression goroutine.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestWriter opens app.log in a temporary directory with its clock
+// replaced. The clock starts at the current time so file modification times
+// written by the OS stay comparable with it.
+func newTestWriter(t *testing.T, config LogConfig) (*RotatingWriter, *testClock) {
+	t.Helper()
+	config.EnableRotation = true
+	w, err := NewRotatingWriter(filepath.Join(t.TempDir(), "app.log"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	w.millWG.Wait()
+
+	clock := &testClock{now: time.Now()}
+	w.mu.Lock()
+	w.now = clock.Now
+	w.day = clock.Now().Format(dayFormat)
+	w.mu.Unlock()
+	return w, clock
+}
+
+func write(t *testing.T, w *RotatingWriter, text string) {
+	t.Helper()
+	if _, err := w.Write([]byte(text)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// rotated waits for background compression and returns the names of the
+// rotated segments, oldest first.
+func rotated(t *testing.T, w *RotatingWriter) []string {
+	t.Helper()
+	w.millWG.Wait()
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != filepath.Base(w.path) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func gunzip(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingWriterRotatesBySize(t *testing.T) {
+	w, clock := newTestWriter(t, LogConfig{})
+	w.maxSize = 10
+
+	write(t, w, "12345")
+	write(t, w, "67890")
+	if names := rotated(t, w); len(names) != 0 {
+		t.Fatalf("rotated at exactly the limit: %v", names)
+	}
+
+	clock.Advance(time.Second)
+	rotatedAt := clock.Now()
+	write(t, w, "abc")
+	names := rotated(t, w)
+	want := "app-" + rotatedAt.Format(backupTimeFormat) + ".log.gz"
+	if len(names) != 1 || names[0] != want {
+		t.Fatalf("backups = %v, want [%s]", names, want)
+	}
+	if got := gunzip(t, filepath.Join(filepath.Dir(w.path), names[0])); got != "1234567890" {
+		t.Errorf("compressed backup holds %q", got)
+	}
+	if got := readFile(t, w.path); got != "abc" {
+		t.Errorf("active file holds %q, want only the new write", got)
+	}
+}
+
+func TestRotatingWriterRotatesAtMidnight(t *testing.T) {
+	w, clock := newTestWriter(t, LogConfig{})
+	year, month, day := time.Now().Date()
+	clock.now = time.Date(year, month, day, 22, 30, 0, 0, time.Local)
+	w.day = clock.now.Format(dayFormat)
+
+	write(t, w, "evening\n")
+	clock.Advance(time.Hour)
+	write(t, w, "before midnight\n")
+	if names := rotated(t, w); len(names) != 0 {
+		t.Fatalf("rotated within a day: %v", names)
+	}
+
+	clock.Advance(time.Hour)
+	write(t, w, "after midnight\n")
+	names := rotated(t, w)
+	if len(names) != 1 || !strings.HasSuffix(names[0], ".log.gz") {
+		t.Fatalf("backups after midnight = %v, want one", names)
+	}
+	if got := gunzip(t, filepath.Join(filepath.Dir(w.path), names[0])); got != "evening\nbefore midnight\n" {
+		t.Errorf("backup holds %q", got)
+	}
+	if got := readFile(t, w.path); got != "after midnight\n" {
+		t.Errorf("active file holds %q", got)
+	}
+}
+
+func TestRotatingWriterDoesNotRotateEmptyFile(t *testing.T) {
+	w, clock := newTestWriter(t, LogConfig{})
+	w.maxSize = 1
+
+	clock.Advance(48 * time.Hour)
+	write(t, w, "a long first line")
+	if names := rotated(t, w); len(names) != 0 {
+		t.Errorf("empty file rotated: %v", names)
+	}
+}
+
+// writeBackup creates a rotated segment last modified at modTime.
+func writeBackup(t *testing.T, w *RotatingWriter, modTime time.Time, compressed bool) string {
+	t.Helper()
+	path := w.backupPath(modTime)
+	if compressed {
+		path += compressedLogExt
+	}
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Base(path)
+}
+
+func TestRotatingWriterKeepsMaxBackups(t *testing.T) {
+	w, clock := newTestWriter(t, LogConfig{MaxBackups: 2})
+	now := clock.Now()
+	writeBackup(t, w, now.Add(-3*time.Hour), true)
+	writeBackup(t, w, now.Add(-2*time.Hour), true)
+	kept := writeBackup(t, w, now.Add(-time.Hour), true)
+
+	write(t, w, "current")
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	names := rotated(t, w)
+	newest := "app-" + now.Format(backupTimeFormat) + ".log.gz"
+	if len(names) != 2 || names[0] != kept || names[1] != newest {
+		t.Errorf("backups = %v, want [%s %s]", names, kept, newest)
+	}
+}
+
+func TestRotatingWriterRemovesOldBackups(t *testing.T) {
+	w, clock := newTestWriter(t, LogConfig{MaxAge: 7})
+	now := clock.Now()
+	writeBackup(t, w, now.AddDate(0, 0, -30), true)
+	writeBackup(t, w, now.AddDate(0, 0, -8), false)
+	recent := writeBackup(t, w, now.AddDate(0, 0, -6), true)
+	unrelated := filepath.Join(filepath.Dir(w.path), "app-notes.log")
+	os.WriteFile(unrelated, []byte("keep"), 0644)
+	os.Chtimes(unrelated, now.AddDate(-1, 0, 0), now.AddDate(-1, 0, 0))
+
+	write(t, w, "current")
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	names := rotated(t, w)
+	newest := "app-" + now.Format(backupTimeFormat) + ".log.gz"
+	if len(names) != 3 || names[0] != recent || names[1] != newest || names[2] != "app-notes.log" {
+		t.Errorf("backups = %v, want %s, %s and the unrelated file", names, recent, newest)
+	}
+}
+
+func TestRotatingWriterCompressesLeftoverBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	stamp := time.Now().Add(-time.Hour)
+	leftover := filepath.Join(dir, "app-"+stamp.Format(backupTimeFormat)+".log")
+	os.WriteFile(leftover, []byte("from the last run"), 0644)
+
+	w, err := NewRotatingWriter(path, LogConfig{EnableRotation: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.millWG.Wait()
+
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Error("uncompressed backup left in place")
+	}
+	if got := gunzip(t, leftover+compressedLogExt); got != "from the last run" {
+		t.Errorf("compressed backup holds %q", got)
+	}
+}
+
+func TestRotatingWriterReopensOnSIGHUP(t *testing.T) {
+	w, _ := newTestWriter(t, LogConfig{})
+	write(t, w, "before\n")
+
+	// logrotate moves the file aside and signals the process
+	moved := w.path + ".1"
+	if err := os.Rename(w.path, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(w.path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("file not reopened after SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	write(t, w, "after\n")
+	if got := readFile(t, w.path); got != "after\n" {
+		t.Errorf("reopened file holds %q", got)
+	}
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("moved file holds %q", got)
+	}
+}
+
+func TestRotatingWriterClosed(t *testing.T) {
+	w, _ := newTestWriter(t, LogConfig{})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("late")); err != os.ErrClosed {
+		t.Errorf("Write after Close: err = %v, want os.ErrClosed", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
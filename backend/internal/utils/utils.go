@@ -7,15 +7,17 @@ import (
 	"main/internal/models"
 	"net/http"
 	"strings"
-
-	"github.com/sirupsen/logrus"
+	"unicode/utf8"
 )
 
+const MaxPostLength = 5000
+
 var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrInvalidInput      = errors.New("invalid input")
 	ErrDuplicateEmail    = errors.New("email already exists")
 	ErrDatabaseOperation = errors.New("database operation failed")
+	ErrPostNotFound      = errors.New("post not found")
 )
 
 func SendJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
@@ -26,15 +28,20 @@ func SendJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
+// SendErrorResponse writes an error body. It includes the request ID set by
+// RequestIDMiddleware so clients can quote it when reporting problems.
 func SendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 	SendJSONResponse(w, statusCode, models.ResponseData{
-		Status:  "error",
-		Message: message,
+		Status:    "error",
+		Message:   message,
+		RequestID: w.Header().Get(RequestIDHeader),
 	})
 }
 
-func HandleError(w http.ResponseWriter, err error, statusCode int, logger *logrus.Entry) {
-	logger.WithError(err).Error("Operation failed")
+// HandleError logs err with the request-scoped logger and sends the matching
+// client-facing message.
+func HandleError(w http.ResponseWriter, r *http.Request, err error, statusCode int) {
+	LoggerFromContext(r.Context()).WithError(err).Error("Operation failed")
 
 	var response models.ResponseData
 	switch {
@@ -44,9 +51,14 @@ func HandleError(w http.ResponseWriter, err error, statusCode int, logger *logru
 		response = models.ResponseData{Status: "error", Message: "Invalid input provided"}
 	case errors.Is(err, ErrDuplicateEmail):
 		response = models.ResponseData{Status: "error", Message: "Email already exists"}
+	case errors.Is(err, ErrInvalidCredentials):
+		response = models.ResponseData{Status: "error", Message: "Invalid email or password"}
+	case errors.Is(err, ErrPostNotFound):
+		response = models.ResponseData{Status: "error", Message: "Post not found"}
 	default:
 		response = models.ResponseData{Status: "error", Message: "Internal server error"}
 	}
+	response.RequestID = w.Header().Get(RequestIDHeader)
 
 	SendJSONResponse(w, statusCode, response)
 }
@@ -61,6 +73,23 @@ func ValidateUser(user models.User) error {
 	if !IsValidEmail(user.UserEmail) {
 		return fmt.Errorf("%w: invalid email format", ErrInvalidInput)
 	}
+	if user.Role != "" && !models.IsValidRole(user.Role) {
+		return fmt.Errorf("%w: unknown role", ErrInvalidInput)
+	}
+	return nil
+}
+
+func ValidatePost(post models.Post) error {
+	if post.UserID == 0 {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidInput)
+	}
+	body := strings.TrimSpace(post.Body)
+	if body == "" {
+		return fmt.Errorf("%w: body is required", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(body) > MaxPostLength {
+		return fmt.Errorf("%w: body must be at most %d characters", ErrInvalidInput, MaxPostLength)
+	}
 	return nil
 }
 
@@ -77,6 +106,9 @@ func IsValidEmail(email string) bool {
 		dotIndex < len(email)-1
 }
 
+// IsDuplicateEmailError reports a unique constraint violation from Postgres
+// or SQLite.
 func IsDuplicateEmailError(err error) bool {
-	return strings.Contains(err.Error(), "duplicate key value violates unique constraint")
+	return strings.Contains(err.Error(), "duplicate key value violates unique constraint") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
 }
@@ -1,22 +1,178 @@
 package database
 
 import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"main/internal/models"
+	"main/internal/config"
+	"main/pkg/metrics"
+	"main/pkg/migrate"
+	"main/pkg/tracing"
 )
 
 var DB *gorm.DB
 
-func InitDB() error {
-	dsn := "host=localhost user=abukhassymkhydyrbayev password=admin dbname=social_pub port=5432 sslmode=disable"
+// driver is the configured database.driver, set by InitDB.
+var driver string
+
+// migrationFiles holds the versioned schema for each driver, see
+// pkg/migrate.
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationDialects maps each supported driver to its migration dialect.
+var migrationDialects = map[string]migrate.Dialect{
+	"postgres": migrate.Postgres,
+	"sqlite":   migrate.SQLite,
+}
+
+func InitDB(cfg config.DatabaseConfig) error {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case "postgres":
+		dialector = postgres.Open(cfg.DatabaseDSN())
+	case "sqlite":
+		if cfg.DSN == "" {
+			if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+				return fmt.Errorf("failed to create database directory: %v", err)
+			}
+		}
+		dialector = sqlite.Open(cfg.DatabaseDSN())
+	default:
+		return fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
 
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(dialector, &gorm.Config{NowFunc: NowUTC})
+	if err != nil {
+		return err
+	}
+	driver = cfg.Driver
+
+	// Export query timings, connection pool statistics and query spans
+	if err := DB.Use(metrics.GormPlugin{}); err != nil {
+		return err
+	}
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return err
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
 		return err
 	}
+	return metrics.RegisterDBStats(sqlDB, cfg.Name)
+}
+
+// NowUTC stamps the created_at and updated_at columns GORM fills in. Every
+// timestamp is stored in UTC: SQLite compares times as text, so rows written
+// with differing offsets would not order or filter correctly.
+func NowUTC() time.Time {
+	return time.Now().UTC()
+}
+
+// Drivers lists the supported database drivers, each with its own
+// migrations directory.
+func Drivers() []string {
+	drivers := make([]string, 0, len(migrationDialects))
+	for name := range migrationDialects {
+		drivers = append(drivers, name)
+	}
+	sort.Strings(drivers)
+	return drivers
+}
+
+// Migrator returns a migrator for the configured driver's schema embedded
+// in the binary.
+func Migrator() (*migrate.Migrator, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+	return MigratorFor(DB, driver)
+}
 
-	// Auto migrate models
-	return DB.AutoMigrate(&models.User{})
+// MigratorFor returns a migrator applying the embedded schema for driver to
+// db, which need not be the one opened by InitDB.
+func MigratorFor(db *gorm.DB, driver string) (*migrate.Migrator, error) {
+	dialect, ok := migrationDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := fs.Sub(migrationFiles, "migrations/"+driver)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := migrate.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, dialect, migrations), nil
+}
+
+// Migrate applies pending migrations and returns those it applied.
+func Migrate(ctx context.Context) ([]migrate.Migration, error) {
+	migrator, err := Migrator()
+	if err != nil {
+		return nil, err
+	}
+	return migrator.Up(ctx)
+}
+
+// Ping checks that the database answers within ctx.
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckMigrations reports migrations this build expects but the database
+// has not applied, e.g. when a deploy skipped `migrate up`.
+func CheckMigrations(ctx context.Context) error {
+	migrator, err := Migrator()
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, migration := range pending {
+			names[i] = migration.String()
+		}
+		return fmt.Errorf("pending migrations: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// Close releases the connection pool opened by InitDB.
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"main/internal/models"
+	"main/pkg/migrate"
+)
+
+// useSQLite points DB at the SQLite database dsn for one test.
+func useSQLite(t *testing.T, dsn string) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousDB, previousDriver := DB, driver
+	DB, driver = db, "sqlite"
+	t.Cleanup(func() {
+		Close()
+		DB, driver = previousDB, previousDriver
+	})
+}
+
+// TestCheckMigrationsOnlyReads checks that the readiness probe neither
+// creates the migrations table nor needs write access.
+func TestCheckMigrationsOnlyReads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	useSQLite(t, "file:"+path)
+	ctx := context.Background()
+
+	err := CheckMigrations(ctx)
+	if err == nil || !strings.Contains(err.Error(), "pending migrations: 0001_") {
+		t.Fatalf("CheckMigrations on an empty database: err = %v, want every migration pending", err)
+	}
+	if DB.Migrator().HasTable(migrate.TableName) {
+		t.Fatalf("CheckMigrations created %s", migrate.TableName)
+	}
+
+	if _, err := Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	Close()
+
+	useSQLite(t, "file:"+path+"?mode=ro")
+	if err := CheckMigrations(ctx); err != nil {
+		t.Errorf("CheckMigrations on a read-only connection: %v", err)
+	}
+}
+
+// TestExistingUsersMarkedVerified checks that accounts created before email
+// verification existed can still post after upgrading.
+func TestExistingUsersMarkedVerified(t *testing.T) {
+	useSQLite(t, "file:"+filepath.Join(t.TempDir(), "test.db"))
+	ctx := context.Background()
+
+	// Stop at the initial schema, as a database from the previous release
+	migrator, err := Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	verified := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	existing := models.User{UserName: "anna", UserEmail: "anna@example.com", Role: models.RoleUser, CreatedAt: created, UpdatedAt: created}
+	already := models.User{UserName: "bob", UserEmail: "bob@example.com", Role: models.RoleUser, EmailVerifiedAt: &verified, CreatedAt: created, UpdatedAt: created}
+	if err := DB.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Create(&already).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].String() != "0002_verify_existing_users" {
+		t.Fatalf("applied %v, want only the backfill", applied)
+	}
+
+	for _, tt := range []struct {
+		id   uint
+		want time.Time
+	}{{existing.UserID, created}, {already.UserID, verified}} {
+		var user models.User
+		if err := DB.First(&user, tt.id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if user.EmailVerifiedAt == nil || !user.EmailVerifiedAt.Equal(tt.want) {
+			t.Errorf("%s: email_verified_at = %v, want %v", user.UserEmail, user.EmailVerifiedAt, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Package dbtest opens throwaway SQLite databases with the application
+// schema for tests.
+package dbtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"main/internal/config"
+	"main/pkg/database"
+)
+
+// Open creates a SQLite database file in a temporary directory, applies the
+// embedded migrations and returns it. The database is closed and removed
+// when the test finishes.
+func Open(tb testing.TB) *gorm.DB {
+	tb.Helper()
+
+	cfg := config.DatabaseConfig{Driver: "sqlite", Path: filepath.Join(tb.TempDir(), "test.db")}
+	db, err := gorm.Open(sqlite.Open(cfg.DatabaseDSN()), &gorm.Config{Logger: logger.Discard, NowFunc: database.NowUTC})
+	if err != nil {
+		tb.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		tb.Fatalf("open test database: %v", err)
+	}
+	tb.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := database.MigratorFor(db, cfg.Driver)
+	if err != nil {
+		tb.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		tb.Fatalf("apply migrations: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,10 @@
+-- 0002_verify_existing_users
+--
+-- Posting requires a verified email. Accounts created before verification
+-- existed never received a link, so they are marked verified as of their
+-- creation. Verification ships in the same release as versioned
+-- migrations, so every account present when this first runs predates it.
+
+UPDATE users
+SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP)
+WHERE email_verified_at IS NULL;
@@ -0,0 +1,7 @@
+-- 0002_verify_existing_users
+--
+-- SQLite version of postgres/0002_verify_existing_users.up.sql.
+
+UPDATE users
+SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP)
+WHERE email_verified_at IS NULL;
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"main/internal/models"
+	"main/internal/utils"
+)
+
+// EnsureAdmin makes sure an administrator account exists for email. A
+// missing account is created with the given password; an existing one is
+// promoted to admin and keeps its current password.
+func EnsureAdmin(name, email, password string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	var user models.User
+	err := DB.Where("user_email = ?", email).First(&user).Error
+	switch {
+	case err == nil:
+		if user.Role == models.RoleAdmin {
+			return nil
+		}
+		return DB.Model(&user).Updates(map[string]interface{}{
+			"role":       models.RoleAdmin,
+			"updated_at": time.Now().UTC(),
+		}).Error
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	// The operator supplied this address, so it does not need verifying
+	now := time.Now().UTC()
+	user = models.User{
+		UserName:        name,
+		UserEmail:       email,
+		Role:            models.RoleAdmin,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := utils.ValidateUser(user); err != nil {
+		return fmt.Errorf("bootstrap admin: %w", err)
+	}
+	if err := utils.ValidatePassword(password); err != nil {
+		return fmt.Errorf("bootstrap admin: %w", err)
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+
+	return DB.Create(&user).Error
+}
@@ -0,0 +1,174 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/jordan-wright/email"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"main/internal/utils"
+	"main/pkg/metrics"
+)
+
+var ErrNotConfigured = errors.New("mailer is not configured")
+
+// Config holds the SMTP settings used to deliver mail.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// Development allows falling back to LogSender when Host is empty.
+	Development bool
+}
+
+// Sender delivers a fully built message.
+type Sender interface {
+	Send(e *email.Email) error
+}
+
+// Checker is implemented by senders that can verify their relay is
+// reachable without sending a message.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// SMTPSender delivers mail through an SMTP relay using PLAIN auth.
+type SMTPSender struct {
+	config Config
+}
+
+func NewSMTPSender(config Config) *SMTPSender {
+	return &SMTPSender{config: config}
+}
+
+func (s *SMTPSender) Send(e *email.Email) error {
+	if e.From == "" {
+		e.From = s.config.From
+	}
+	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	return e.Send(addr, auth)
+}
+
+// Check connects to the relay, waits for its greeting and hangs up.
+func (s *SMTPSender) Check(ctx context.Context) error {
+	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// LogSender writes messages to the application log instead of sending them.
+// It is used in development when no SMTP relay is configured. Bodies carry
+// live reset and verification tokens, so they are only logged at debug level.
+type LogSender struct {
+	From string
+}
+
+func (s *LogSender) Send(e *email.Email) error {
+	if e.From == "" {
+		e.From = s.From
+	}
+	entry := utils.Log.WithFields(logrus.Fields{
+		"from":    e.From,
+		"to":      strings.Join(e.To, ", "),
+		"subject": e.Subject,
+	})
+	entry.Info("Mail relay not configured, logging email instead of sending it")
+	entry.WithField("body", string(e.Text)).Debug("Body of logged email")
+	return nil
+}
+
+var sender Sender
+
+// Init selects the SMTP sender when a host is configured. Without a host it
+// falls back to the log sender in development and fails otherwise.
+func Init(config Config) error {
+	if config.Host == "" {
+		if !config.Development {
+			return fmt.Errorf("%w: an SMTP host is required outside development", ErrNotConfigured)
+		}
+		sender = &LogSender{From: config.From}
+		return nil
+	}
+	sender = NewSMTPSender(config)
+	return nil
+}
+
+// SetSender replaces the active sender.
+func SetSender(s Sender) {
+	sender = s
+}
+
+var tracer = otel.Tracer("main/pkg/mailer")
+
+// Send delivers e through the active sender. The delivery is recorded as a
+// span under the one in ctx.
+func Send(ctx context.Context, e *email.Email) error {
+	if sender == nil {
+		return ErrNotConfigured
+	}
+
+	_, span := tracer.Start(ctx, "smtp.send", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("mail.sender", fmt.Sprintf("%T", sender)),
+		attribute.Int("mail.recipients", len(e.To)+len(e.Cc)+len(e.Bcc)),
+	)
+	if s, ok := sender.(*SMTPSender); ok {
+		span.SetAttributes(semconv.ServerAddress(s.config.Host), semconv.ServerPort(s.config.Port))
+	}
+
+	if err := sender.Send(e); err != nil {
+		metrics.EmailsSent.WithLabelValues("failed").Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	metrics.EmailsSent.WithLabelValues("sent").Inc()
+	return nil
+}
+
+// Check reports whether the active sender can deliver mail. Senders that do
+// not implement Checker are assumed to be available.
+func Check(ctx context.Context) error {
+	if sender == nil {
+		return ErrNotConfigured
+	}
+	if checker, ok := sender.(Checker); ok {
+		return checker.Check(ctx)
+	}
+	return nil
+}
+
+// SendText delivers a plain-text message to a single recipient.
+func SendText(ctx context.Context, to, subject, body string) error {
+	e := email.NewEmail()
+	e.To = []string{to}
+	e.Subject = subject
+	e.Text = []byte(body)
+	return Send(ctx, e)
+}
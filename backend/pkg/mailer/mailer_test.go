@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jordan-wright/email"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"main/internal/utils"
+)
+
+func TestLogSenderKeepsBodyOutOfInfo(t *testing.T) {
+	previous := utils.Log
+	t.Cleanup(func() { utils.Log = previous })
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.InfoLevel)
+	utils.Log = logger
+
+	e := email.NewEmail()
+	e.To = []string{"anna@example.com"}
+	e.Subject = "Reset your password"
+	e.Text = []byte("https://example.com/reset?token=secret-token")
+	if err := (&LogSender{From: "noreply@example.com"}).Send(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hook.Entries) != 1 {
+		t.Fatalf("got %d entries at info level, want 1", len(hook.Entries))
+	}
+	entry := hook.LastEntry()
+	if entry.Data["to"] != "anna@example.com" || entry.Data["subject"] != "Reset your password" {
+		t.Errorf("entry fields = %v", entry.Data)
+	}
+	for key, value := range entry.Data {
+		if strings.Contains(value.(string), "secret-token") {
+			t.Errorf("field %s leaks the body at info level", key)
+		}
+	}
+
+	// The body is still available when debugging locally
+	hook.Reset()
+	logger.SetLevel(logrus.DebugLevel)
+	(&LogSender{}).Send(e)
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.DebugLevel || entry.Data["body"] != string(e.Text) {
+		t.Errorf("body not logged at debug level: %+v", entry)
+	}
+}
+
+func TestInitRequiresHostOutsideDevelopment(t *testing.T) {
+	previous := sender
+	t.Cleanup(func() { sender = previous })
+
+	sender = nil
+	if err := Init(Config{From: "noreply@example.com"}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Init without a host: err = %v, want ErrNotConfigured", err)
+	}
+	if sender != nil {
+		t.Errorf("Init without a host selected %T", sender)
+	}
+
+	if err := Init(Config{From: "noreply@example.com", Development: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sender.(*LogSender); !ok {
+		t.Errorf("Init in development selected %T, want *LogSender", sender)
+	}
+
+	if err := Init(Config{Host: "smtp.example.com", Port: 587}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sender.(*SMTPSender); !ok {
+		t.Errorf("Init with a host selected %T, want *SMTPSender", sender)
+	}
+}
@@ -0,0 +1,253 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+func file(contents string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(contents)}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0002_add_posts.up.sql":      file("CREATE TABLE posts (id INTEGER)"),
+		"0002_add_posts.down.sql":    file("DROP TABLE posts"),
+		"0001_create_users.up.sql":   file("CREATE TABLE users (id INTEGER)"),
+		"0001_create_users.down.sql": file("DROP TABLE users"),
+		"README.md":                  file("not a migration"),
+		"archive/0009_old.up.sql":    file("ignored"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].String() != "0001_create_users" || migrations[1].String() != "0002_add_posts" {
+		t.Fatalf("got %v, want 0001_create_users and 0002_add_posts in order", migrations)
+	}
+	if migrations[1].Up != "CREATE TABLE posts (id INTEGER)" || migrations[1].Down != "DROP TABLE posts" {
+		t.Errorf("statements not loaded: %+v", migrations[1])
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		desc    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{"bad file name", fstest.MapFS{
+			"1-create-users.up.sql": file("SELECT 1"),
+		}, "name must look like"},
+		{"upper case name", fstest.MapFS{
+			"0001_CreateUsers.up.sql": file("SELECT 1"),
+		}, "name must look like"},
+		{"version zero", fstest.MapFS{
+			"0000_create_users.up.sql":   file("SELECT 1"),
+			"0000_create_users.down.sql": file("SELECT 1"),
+		}, "invalid version"},
+		{"duplicate version", fstest.MapFS{
+			"0001_create_users.up.sql":   file("SELECT 1"),
+			"0001_create_users.down.sql": file("SELECT 1"),
+			"0001_create_posts.up.sql":   file("SELECT 1"),
+			"0001_create_posts.down.sql": file("SELECT 1"),
+		}, "version 1 is used by both"},
+		{"missing down", fstest.MapFS{
+			"0001_create_users.up.sql": file("SELECT 1"),
+		}, "needs both an up and a down file"},
+		{"empty up", fstest.MapFS{
+			"0001_create_users.up.sql":   file("  \n"),
+			"0001_create_users.down.sql": file("SELECT 1"),
+		}, "needs both an up and a down file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id INTEGER)", Down: "DROP TABLE users"},
+	{Version: 2, Name: "create_posts", Up: "CREATE TABLE posts (id INTEGER)", Down: "DROP TABLE posts"},
+	{Version: 3, Name: "create_tags", Up: "CREATE TABLE tags (id INTEGER)", Down: "DROP TABLE tags"},
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	t.Helper()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	exists, err := SQLite.TableExists(context.Background(), conn, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func names(migrations []Migration) string {
+	parts := make([]string, len(migrations))
+	for i, migration := range migrations {
+		parts[i] = migration.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestUpDown(t *testing.T) {
+	db := openDB(t)
+	migrator := New(db, SQLite, testMigrations)
+	ctx := context.Background()
+
+	// Reading the status before the first Up leaves the database untouched
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(pending); got != "0001_create_users,0002_create_posts,0003_create_tags" {
+		t.Errorf("pending before Up = %s", got)
+	}
+	if tableExists(t, db, TableName) {
+		t.Fatalf("Pending created %s", TableName)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 3 || !tableExists(t, db, "tags") {
+		t.Fatalf("Up applied %s", names(applied))
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second Up applied %s, %v", names(applied), err)
+	}
+
+	reverted, err := migrator.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(reverted); got != "0003_create_tags,0002_create_posts" {
+		t.Errorf("Down(2) reverted %s, want the newest two", got)
+	}
+	if tableExists(t, db, "posts") || !tableExists(t, db, "users") {
+		t.Error("Down(2) did not drop exactly posts and tags")
+	}
+	if pending, _ := migrator.Pending(ctx); names(pending) != "0002_create_posts,0003_create_tags" {
+		t.Errorf("pending after Down(2) = %s", names(pending))
+	}
+
+	// Asking for more steps than are applied reverts the rest
+	if reverted, err := migrator.Down(ctx, 5); err != nil || names(reverted) != "0001_create_users" {
+		t.Errorf("Down(5) reverted %s, %v", names(reverted), err)
+	}
+}
+
+func TestUpStopsAtFailingMigration(t *testing.T) {
+	db := openDB(t)
+	broken := append([]Migration{}, testMigrations[:2]...)
+	broken[1].Up = "CREATE TABLE posts (id INTEGER); NOT SQL"
+	ctx := context.Background()
+
+	applied, err := New(db, SQLite, broken).Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "0002_create_posts") {
+		t.Fatalf("err = %v, want one naming the failing migration", err)
+	}
+	if names(applied) != "0001_create_users" {
+		t.Errorf("applied %s before the failure", names(applied))
+	}
+	if tableExists(t, db, "posts") {
+		t.Error("failing migration was not rolled back")
+	}
+}
+
+func TestStatusListsUnknownVersions(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	if _, err := New(db, SQLite, testMigrations).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// An older build only knows the first migration
+	statuses, err := New(db, SQLite, testMigrations[:1]).Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("got %d statuses, want 3", len(statuses))
+	}
+	for i, want := range []string{"0001_create_users", "0002_create_posts", "0003_create_tags"} {
+		if statuses[i].String() != want || statuses[i].AppliedAt == nil {
+			t.Errorf("status %d = %s applied at %v, want %s applied", i, statuses[i], statuses[i].AppliedAt, want)
+		}
+	}
+	if statuses[1].Up != "" || statuses[1].Down != "" {
+		t.Error("unknown migration has statements")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	up, down, err := Create(dir, "Create Users!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0001_create_users.up.sql" || filepath.Base(down) != "0001_create_users.down.sql" {
+		t.Errorf("created %s and %s", up, down)
+	}
+
+	// The next migration is numbered after the highest existing version
+	os.WriteFile(filepath.Join(dir, "0007_add_tags.up.sql"), []byte("SELECT 1"), 0644)
+	os.WriteFile(filepath.Join(dir, "0007_add_tags.down.sql"), []byte("SELECT 1"), 0644)
+	up, _, err = Create(dir, "add-post-titles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0008_add_post_titles.up.sql" {
+		t.Errorf("created %s, want 0008_add_post_titles.up.sql", up)
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("created files do not load: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Errorf("loaded %d migrations, want 3", len(migrations))
+	}
+
+	if _, _, err := Create(dir, " -- "); err == nil {
+		t.Error("name without letters or digits accepted")
+	}
+}
+
+func TestCreateRefusesInvalidDirectory(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "0001_create_users.up.sql"), []byte("SELECT 1"), 0644)
+
+	if _, _, err := Create(dir, "add_posts"); err == nil {
+		t.Error("Create succeeded next to a migration without a down file")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Create left %d files, want only the existing one", len(entries))
+	}
+}
@@ -0,0 +1,38 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// postgresLockKey identifies the advisory lock serialising migrations. Any
+// fixed value works as long as nothing else in the database uses it.
+const postgresLockKey int64 = 0x6d6967726174 // "migrat"
+
+// Postgres serialises migrations with a session-level advisory lock.
+var Postgres Dialect = postgresDialect{}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockKey)
+	return err
+}
+
+func (postgresDialect) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", postgresLockKey)
+	return err
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// TableExists resolves table through the search path like an unqualified
+// query would.
+func (postgresDialect) TableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	return exists, err
+}
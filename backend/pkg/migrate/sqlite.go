@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// SQLite serialises migrations within the process. SQLite databases are
+// local files, so deployments using it run a single instance.
+var SQLite Dialect = &sqliteDialect{}
+
+type sqliteDialect struct {
+	mu sync.Mutex
+}
+
+func (d *sqliteDialect) Lock(ctx context.Context, conn *sql.Conn) error {
+	d.mu.Lock()
+	return nil
+}
+
+func (d *sqliteDialect) Unlock(ctx context.Context, conn *sql.Conn) error {
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (d *sqliteDialect) TableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", table).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a budget of Requests per Window on average, with up to Burst
+// requests allowed back to back. Requests that would have to wait at most
+// MaxWait are delayed instead of rejected.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+	Burst    int
+	MaxWait  time.Duration
+}
+
+// Unlimited reports whether the limit lets every request through.
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Window <= 0
+}
+
+// interval is the time one request "costs" under the GCRA algorithm.
+func (l Limit) interval() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+// burstOffset is how far ahead of now the theoretical arrival time may run.
+func (l Limit) burstOffset() time.Duration {
+	return l.interval() * time.Duration(max(1, l.Burst))
+}
+
+// Result describes the outcome of a single Allow call.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	// Reset is how long until the client's budget is fully replenished.
+	Reset time.Duration
+}
+
+// Store decides whether the client identified by key may make another
+// request under limit. Implementations must be safe for concurrent use.
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// gcra applies the generic cell rate algorithm to a client whose theoretical
+// arrival time is tat. It returns the outcome and the new TAT to store; the
+// new TAT equals tat when the request is rejected.
+func gcra(limit Limit, now, tat time.Time) (Result, time.Time) {
+	interval := limit.interval()
+	offset := limit.burstOffset()
+
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(interval)
+	allowAt := newTAT.Add(-offset)
+
+	if now.Before(allowAt) {
+		return Result{
+			Allowed:    false,
+			Remaining:  0,
+			RetryAfter: allowAt.Sub(now),
+			Reset:      tat.Sub(now),
+		}, tat
+	}
+
+	return Result{
+		Allowed:   true,
+		Remaining: remaining(offset, newTAT.Sub(now), interval),
+		Reset:     newTAT.Sub(now),
+	}, newTAT
+}
+
+func remaining(offset, used, interval time.Duration) int {
+	return int(math.Max(0, math.Floor(float64(offset-used)/float64(interval))))
+}
@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// clockedStore is a store under test together with a way to move its clock.
+type clockedStore struct {
+	Store
+	advance func(d time.Duration)
+}
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newMemoryStore(t *testing.T) clockedStore {
+	store := NewMemoryStore()
+	now := start
+	store.now = func() time.Time { return now }
+	store.lastSweep = now
+	return clockedStore{Store: store, advance: func(d time.Duration) { now = now.Add(d) }}
+}
+
+func newRedisStore(t *testing.T) clockedStore {
+	server := miniredis.RunT(t)
+	now := start
+	server.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return clockedStore{
+		Store: NewRedisStore(client, "ratelimit:"),
+		advance: func(d time.Duration) {
+			now = now.Add(d)
+			server.SetTime(now)
+		},
+	}
+}
+
+// stores runs test against the memory store and a Redis store backed by
+// miniredis, which share the GCRA semantics.
+func stores(t *testing.T, test func(t *testing.T, store clockedStore)) {
+	t.Run("memory", func(t *testing.T) { test(t, newMemoryStore(t)) })
+	t.Run("redis", func(t *testing.T) { test(t, newRedisStore(t)) })
+}
+
+// tenPerMinute refills one request every six seconds with a burst of five.
+var tenPerMinute = Limit{Requests: 10, Window: time.Minute, Burst: 5}
+
+func allow(t *testing.T, store Store, key string, limit Limit) Result {
+	t.Helper()
+	result, err := store.Allow(context.Background(), key, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestBurst(t *testing.T) {
+	stores(t, func(t *testing.T, store clockedStore) {
+		for i := 0; i < tenPerMinute.Burst; i++ {
+			result := allow(t, store, "client", tenPerMinute)
+			if !result.Allowed {
+				t.Fatalf("request %d of the burst rejected", i+1)
+			}
+			if want := tenPerMinute.Burst - 1 - i; result.Remaining != want {
+				t.Errorf("request %d: remaining = %d, want %d", i+1, result.Remaining, want)
+			}
+		}
+		result := allow(t, store, "client", tenPerMinute)
+		if result.Allowed || result.Remaining != 0 {
+			t.Errorf("request after the burst: %+v, want rejected", result)
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	stores(t, func(t *testing.T, store clockedStore) {
+		for i := 0; i < tenPerMinute.Burst; i++ {
+			allow(t, store, "client", tenPerMinute)
+		}
+		store.advance(2 * time.Second)
+
+		result := allow(t, store, "client", tenPerMinute)
+		if result.Allowed {
+			t.Fatal("request allowed before the next refill")
+		}
+		if want := 4 * time.Second; result.RetryAfter != want {
+			t.Errorf("retry after = %v, want %v", result.RetryAfter, want)
+		}
+		if want := 28 * time.Second; result.Reset != want {
+			t.Errorf("reset = %v, want %v", result.Reset, want)
+		}
+
+		// Rejected requests do not push the client further back
+		store.advance(result.RetryAfter)
+		if !allow(t, store, "client", tenPerMinute).Allowed {
+			t.Error("request rejected after waiting retry-after")
+		}
+	})
+}
+
+func TestRefill(t *testing.T) {
+	stores(t, func(t *testing.T, store clockedStore) {
+		for i := 0; i < tenPerMinute.Burst; i++ {
+			allow(t, store, "client", tenPerMinute)
+		}
+
+		// One interval buys back exactly one request
+		store.advance(6 * time.Second)
+		if !allow(t, store, "client", tenPerMinute).Allowed {
+			t.Fatal("request rejected after one interval")
+		}
+		if allow(t, store, "client", tenPerMinute).Allowed {
+			t.Fatal("second request allowed after one interval")
+		}
+
+		// Idling for longer than the burst takes to refill restores the
+		// whole burst but no more
+		store.advance(time.Hour)
+		for i := 0; i < tenPerMinute.Burst; i++ {
+			if !allow(t, store, "client", tenPerMinute).Allowed {
+				t.Fatalf("request %d rejected after a full refill", i+1)
+			}
+		}
+		if allow(t, store, "client", tenPerMinute).Allowed {
+			t.Error("burst exceeded after a long idle period")
+		}
+	})
+}
+
+func TestKeyIsolation(t *testing.T) {
+	stores(t, func(t *testing.T, store clockedStore) {
+		for i := 0; i < tenPerMinute.Burst+1; i++ {
+			allow(t, store, "noisy", tenPerMinute)
+		}
+		if allow(t, store, "noisy", tenPerMinute).Allowed {
+			t.Fatal("noisy client not limited")
+		}
+		result := allow(t, store, "quiet", tenPerMinute)
+		if !result.Allowed || result.Remaining != tenPerMinute.Burst-1 {
+			t.Errorf("quiet client affected by another key: %+v", result)
+		}
+	})
+}
+
+func TestUnlimited(t *testing.T) {
+	stores(t, func(t *testing.T, store clockedStore) {
+		for i := 0; i < 100; i++ {
+			if !allow(t, store, "client", Limit{}).Allowed {
+				t.Fatal("request rejected without a limit")
+			}
+		}
+	})
+}
+
+func TestMemoryStoreEvictsIdleClients(t *testing.T) {
+	store := NewMemoryStore()
+	now := start
+	store.now = func() time.Time { return now }
+	store.lastSweep = now
+
+	allow(t, store, "client", tenPerMinute)
+	now = now.Add(2 * memorySweepInterval)
+	allow(t, store, "other", tenPerMinute)
+
+	if _, ok := store.tats["client"]; ok {
+		t.Error("idle client kept after its budget refilled")
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript runs the same algorithm as gcra atomically on the server, using
+// the server clock so replicas with skewed clocks agree. Times are in
+// microseconds.
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local offset = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - offset
+if now < allow_at then
+  return {0, allow_at - now, tat - now}
+end
+
+-- format explicitly: plain tostring would use %.14g and lose precision
+redis.call('SET', KEYS[1], string.format('%d', new_tat), 'PX', string.format('%d', math.ceil((new_tat - now) / 1000)))
+return {1, 0, new_tat - now}
+`)
+
+// RedisStore keeps limiter state in Redis so every replica shares the same
+// budget per client.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore returns a store that namespaces its keys under prefix.
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true, Remaining: limit.Burst}, nil
+	}
+
+	interval := limit.interval()
+	offset := limit.burstOffset()
+
+	values, err := gcraScript.Run(ctx, s.client, []string{s.prefix + key},
+		interval.Microseconds(), offset.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	retryAfter := time.Duration(values[1]) * time.Microsecond
+	reset := time.Duration(values[2]) * time.Microsecond
+	if values[0] == 0 {
+		return Result{Allowed: false, RetryAfter: retryAfter, Reset: reset}, nil
+	}
+	return Result{
+		Allowed:   true,
+		Remaining: remaining(offset, reset, interval),
+		Reset:     reset,
+	}, nil
+}
@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT * FROM users WHERE user_email = 'anna@example.com'`, `SELECT * FROM users WHERE user_email = ?`},
+		{`SELECT * FROM users WHERE user_name = 'O''Brien' AND role = 'admin'`, `SELECT * FROM users WHERE user_name = ? AND role = ?`},
+		{`SELECT * FROM users WHERE user_name = ''`, `SELECT * FROM users WHERE user_name = ?`},
+		{`SELECT * FROM posts WHERE user_id = 42 LIMIT 10 OFFSET 20`, `SELECT * FROM posts WHERE user_id = ? LIMIT ? OFFSET ?`},
+		{`UPDATE users SET totp_last_step = 56789012 WHERE user_id=7`, `UPDATE users SET totp_last_step = ? WHERE user_id=?`},
+		{`SELECT * FROM scores WHERE value > -1.5 AND value < 2.25`, `SELECT * FROM scores WHERE value > ? AND value < ?`},
+		{`SELECT * FROM users WHERE user_id IN (1,2,3)`, `SELECT * FROM users WHERE user_id IN (?,?,?)`},
+		{`SELECT * FROM t WHERE note = 'call 555 1234'`, `SELECT * FROM t WHERE note = ?`},
+
+		// Bound parameters, identifiers and quoted names containing digits stay
+		{`SELECT * FROM users WHERE user_id = $1 AND user_email = $2`, `SELECT * FROM users WHERE user_id = $1 AND user_email = $2`},
+		{`SELECT * FROM users WHERE user_id = ?`, `SELECT * FROM users WHERE user_id = ?`},
+		{`SELECT t1.col2 FROM table3 AS t1`, `SELECT t1.col2 FROM table3 AS t1`},
+		{`SELECT "2fa_enabled" FROM "users"`, `SELECT "2fa_enabled" FROM "users"`},
+		{`INSERT INTO schema_migrations (version) VALUES ($1)`, `INSERT INTO schema_migrations (version) VALUES ($1)`},
+	}
+	for _, tt := range tests {
+		if got := SanitizeSQL(tt.query); got != tt.want {
+			t.Errorf("SanitizeSQL(%q)\n got %q\nwant %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+var exporter *tracetest.InMemoryExporter
+
+// recordSpans installs a tracer provider that keeps finished spans in
+// memory. The global tracer delegates to the first provider installed, so
+// tests in this package share one exporter and reset it.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	if exporter == nil {
+		exporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	}
+	exporter.Reset()
+	return exporter
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.Exec(`CREATE TABLE users (user_id INTEGER PRIMARY KEY, user_email TEXT)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRouteSpanWithGormChild(t *testing.T) {
+	spans := recordSpans(t)
+	db := openDB(t)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RecordRoute(r.Context(), r.Method, "/api/v1/users/{id}")
+		var found []map[string]any
+		db.WithContext(r.Context()).Table("users").Where("user_email = 'anna@example.com' AND user_id = 7").Find(&found)
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users/7", nil))
+
+	ended := spans.GetSpans()
+	if len(ended) != 2 {
+		t.Fatalf("got %d spans, want the query and the request", len(ended))
+	}
+	query, server := ended[0], ended[1]
+
+	if server.Name != "GET /api/v1/users/{id}" {
+		t.Errorf("server span named %q", server.Name)
+	}
+	if !hasAttribute(server, string(semconv.HTTPRouteKey), "/api/v1/users/{id}") {
+		t.Errorf("server span attributes %v lack http.route", server.Attributes)
+	}
+
+	if query.Name != "gorm.query" {
+		t.Errorf("query span named %q", query.Name)
+	}
+	if query.Parent.SpanID() != server.SpanContext.SpanID() || query.SpanContext.TraceID() != server.SpanContext.TraceID() {
+		t.Error("query span is not a child of the request span")
+	}
+	if !hasAttribute(query, string(semconv.DBCollectionNameKey), "users") {
+		t.Errorf("query span attributes %v lack the table", query.Attributes)
+	}
+	for _, attr := range query.Attributes {
+		if attr.Key == semconv.DBQueryTextKey {
+			text := attr.Value.AsString()
+			if strings.Contains(text, "anna@example.com") || strings.Contains(text, "7") {
+				t.Errorf("query text %q carries literals", text)
+			}
+			if !strings.Contains(text, "user_email = ? AND user_id = ?") {
+				t.Errorf("query text %q", text)
+			}
+		}
+	}
+}
+
+func TestGormWithoutRequestSpan(t *testing.T) {
+	spans := recordSpans(t)
+	db := openDB(t)
+
+	var count int64
+	db.Table("users").Count(&count)
+	if ended := spans.GetSpans(); len(ended) != 0 {
+		t.Errorf("query outside a request recorded %d spans", len(ended))
+	}
+}
+
+func hasAttribute(span tracetest.SpanStub, key, value string) bool {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key && attr.Value.AsString() == value {
+			return true
+		}
+	}
+	return false
+}